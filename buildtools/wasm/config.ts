@@ -0,0 +1,228 @@
+import { err as stderr, in as stdin } from "qjs:std";
+
+// Evaluates JS config files. The guest has no filesystem, so the host sends the
+// config source and serves the modules it requires, only allowing relative
+// requires, along with whether each is an ES module or CommonJS. QuickJS can only
+// load ES modules from the filesystem, so ES module syntax is rewritten to
+// CommonJS, which covers the forms used by configs.
+// https://github.com/prettier/prettier/blob/main/src/config/prettier-config/loaders.js
+
+type Module = { exports: any };
+
+const modules = new Map<string, Module>();
+
+function dirname(path: string): string {
+  const i = Math.max(path.lastIndexOf("/"), path.lastIndexOf("\\"));
+  return i <= 0 ? path.slice(0, 1) : path.slice(0, i);
+}
+
+// Module types as in package.json, determined by the host.
+type ModuleType = "module" | "commonjs";
+
+// codeLineStarts returns the offsets of the lines of source that start outside
+// of comments, strings and template literals, where import and export
+// statements can be. Regular expression literals are not recognized, which is
+// fine for configs.
+function codeLineStarts(source: string): Set<number> {
+  const starts = new Set<number>([0]);
+  // Brace depths at which the substitutions of enclosing template literals end.
+  const substitutions: number[] = [];
+  let braces = 0;
+  let inTemplate = false;
+  for (let i = 0; i < source.length; i++) {
+    const c = source[i];
+    if (inTemplate) {
+      if (c === "\\") {
+        i++;
+      } else if (c === "`") {
+        inTemplate = false;
+      } else if (c === "$" && source[i + 1] === "{") {
+        substitutions.push(braces);
+        braces++;
+        i++;
+        inTemplate = false;
+      }
+      continue;
+    }
+    switch (c) {
+      case "\n":
+        starts.add(i + 1);
+        break;
+      case "`":
+        inTemplate = true;
+        break;
+      case "{":
+        braces++;
+        break;
+      case "}":
+        braces--;
+        if (substitutions.length > 0 && substitutions[substitutions.length - 1] === braces) {
+          substitutions.pop();
+          inTemplate = true;
+        }
+        break;
+      case '"':
+      case "'":
+        for (i++; i < source.length && source[i] !== c && source[i] !== "\n"; i++) {
+          if (source[i] === "\\") {
+            i++;
+          }
+        }
+        break;
+      case "/":
+        if (source[i + 1] === "/") {
+          const end = source.indexOf("\n", i);
+          i = end < 0 ? source.length : end - 1;
+        } else if (source[i + 1] === "*") {
+          const end = source.indexOf("*/", i + 2);
+          i = end < 0 ? source.length : end + 1;
+        }
+        break;
+    }
+  }
+  return starts;
+}
+
+// replaceStatements is like source.replace with a multiline pattern matching
+// from the start of a line, but leaves matches in comments, strings and template
+// literals as is.
+function replaceStatements(
+  source: string,
+  pattern: RegExp,
+  replacer: (...args: any[]) => string,
+): string {
+  const starts = codeLineStarts(source);
+  return source.replace(pattern, (...args: any[]) => {
+    // Without named groups, the offset is second to last.
+    const offset: number = args[args.length - 2];
+    return starts.has(offset) ? replacer(...args) : args[0];
+  });
+}
+
+function importBinding(binding: string, module: string): string {
+  binding = binding.trim();
+  if (binding.startsWith("* as ")) {
+    return `const ${binding.slice(5).trim()} = ${module};`;
+  }
+  if (binding.startsWith("{")) {
+    return `const ${binding.replace(/\s+as\s+/g, ": ")} = ${module};`;
+  }
+  return `const ${binding} = __importDefault(${module});`;
+}
+
+function esmToCommonJS(source: string): string {
+  const exported: string[] = [];
+  let imports = 0;
+  let res = replaceStatements(
+    source,
+    /^([ \t]*)import\s+([\w$]+\s*,\s*)?([\w$]+|\*\s*as\s+[\w$]+|\{[^}]*\})\s+from\s*(["'][^"']+["'])\s*;?/gm,
+    (_, indent: string, defaultBinding: string | undefined, binding: string, spec: string) => {
+      if (!defaultBinding) {
+        return indent + importBinding(binding, `require(${spec})`);
+      }
+      const name = `__module${imports++}`;
+      return `${indent}const ${name} = require(${spec}); ${importBinding(defaultBinding.replace(",", ""), name)} ${importBinding(binding, name)}`;
+    },
+  );
+  res = replaceStatements(
+    res,
+    /^([ \t]*)import\s*(["'][^"']+["'])\s*;?/gm,
+    (_, indent: string, spec: string) => `${indent}require(${spec});`,
+  );
+  res = replaceStatements(
+    res,
+    /^([ \t]*)export\s+default\s+/gm,
+    (_, indent: string) => `${indent}exports.default = `,
+  );
+  res = replaceStatements(
+    res,
+    /^([ \t]*)export\s+(const|let|var|function\*?|async\s+function|class)\s+([\w$]+)/gm,
+    (_, indent: string, kind: string, name: string) => {
+      exported.push(name);
+      return `${indent}${kind} ${name}`;
+    },
+  );
+  res = replaceStatements(
+    res,
+    /^([ \t]*)export\s*\{([^}]*)\}\s*;?/gm,
+    (_, indent: string, names: string) => {
+      const assignments = names
+        .split(",")
+        .map((n) => n.trim())
+        .filter((n) => n)
+        .map((n) => {
+          const [local, as] = n.split(/\s+as\s+/);
+          return `exports[${JSON.stringify(as ?? local)}] = ${local};`;
+        });
+      return indent + assignments.join(" ");
+    },
+  );
+  for (const name of exported) {
+    res += `\nexports.${name} = ${name};`;
+  }
+  return `Object.defineProperty(exports, "__esModule", { value: true });\n${res}`;
+}
+
+function __importDefault(module: any): any {
+  return module?.__esModule ? module.default : module;
+}
+
+function requireModule(from: string, specifier: string): any {
+  stderr.puts(JSON.stringify({ name: "module-request", body: specifier, path: from }));
+  stderr.puts("\n");
+  stderr.flush();
+  const response = JSON.parse(stdin.getline()!);
+  if (response.name !== "module-response") {
+    throw new Error(`Expected response name to be "module-response", got "${response.name}"`);
+  }
+  if (response.error) {
+    throw new Error(response.error.message);
+  }
+  return evaluateModule(response.path, response.body, response.moduleType).exports;
+}
+
+function evaluateModule(path: string, source: string, moduleType: ModuleType): Module {
+  const cached = modules.get(path);
+  if (cached) {
+    return cached;
+  }
+
+  const module: Module = { exports: {} };
+  modules.set(path, module);
+  if (path.endsWith(".json")) {
+    module.exports = JSON.parse(source);
+    return module;
+  }
+
+  const code = moduleType === "module" ? esmToCommonJS(source) : source;
+  const fn = new Function(
+    "module",
+    "exports",
+    "require",
+    "__importDefault",
+    "__filename",
+    "__dirname",
+    code,
+  );
+  fn(
+    module,
+    module.exports,
+    (specifier: string) => requireModule(path, specifier),
+    __importDefault,
+    path,
+    dirname(path),
+  );
+  return module;
+}
+
+// evalConfig evaluates the config file at path with source, of moduleType,
+// returning the config it exports. Like importing the config in Node, the
+// default export of an ES module is used and CommonJS exports are used as is.
+export async function evalConfig(
+  path: string,
+  source: string,
+  moduleType: ModuleType,
+): Promise<any> {
+  const module = evaluateModule(path, source, moduleType);
+  return await __importDefault(module.exports);
+}
@@ -2,7 +2,7 @@ import "./global.js";
 import "./settimeout.js";
 import "./textcoding.js";
 
-import { format } from "prettier";
+import { formatWithCursor, getSupportInfo } from "prettier";
 import pluginAcorn from "prettier/plugins/acorn.js";
 import pluginAngular from "prettier/plugins/angular.js";
 import pluginBabel from "prettier/plugins/babel.js";
@@ -16,54 +16,147 @@ import pluginPostcss from "prettier/plugins/postcss.js";
 import pluginTypescript from "prettier/plugins/typescript.js";
 import pluginYaml from "prettier/plugins/yaml.js";
 
+import { evalConfig } from "./config.js";
 import pluginGo from "./go/index.js";
 import pluginSh from "./sh/index.js";
 
-import { exit, err as stderr, in as stdin, out as stdout } from "qjs:std";
+import { err as stderr, in as stdin, out as stdout } from "qjs:std";
 
-async function run() {
-  const config = JSON.parse(scriptArgs[1]);
+const plugins = [
+  pluginAcorn,
+  pluginAngular,
+  pluginBabel,
+  pluginEsTree,
+  pluginGlimmer,
+  pluginGo,
+  pluginHtml,
+  pluginGraphQl,
+  pluginMarkdown,
+  pluginMeriyah,
+  pluginPostcss,
+  pluginSh,
+  pluginTypescript,
+  pluginYaml,
+];
 
-  const inputStr = stdin.getline();
-  const inputMsg = JSON.parse(inputStr);
-  const content = inputMsg.body;
+function send(msg: any) {
+  stderr.printf("%s\n", JSON.stringify(msg));
+  stderr.flush();
+}
 
-  let response: string;
+function sendError(e: any) {
+  let message: string = e.message;
+  // The host renders location and code frame itself so remove them from the
+  // message prettier builds.
+  if (e.codeFrame && message.endsWith(`\n${e.codeFrame}`)) {
+    message = message.slice(0, -(e.codeFrame.length + 1));
+  }
+  if (e.loc) {
+    message = message.replace(/ \(\d+:\d+\)$/, "");
+  }
+  send({
+    name: "error",
+    body: "",
+    error: {
+      name: e.name,
+      message,
+      loc: e.loc,
+    },
+  });
+}
 
+async function handleFormat(msg: any) {
+  let response: { formatted: string; cursorOffset: number };
   try {
-    response = await format(content, {
-      ...config,
-      plugins: [
-        pluginAcorn,
-        pluginAngular,
-        pluginBabel,
-        pluginEsTree,
-        pluginGlimmer,
-        pluginGo,
-        pluginHtml,
-        pluginGraphQl,
-        pluginMarkdown,
-        pluginMeriyah,
-        pluginPostcss,
-        pluginSh,
-        pluginTypescript,
-        pluginYaml,
-      ],
+    // formatWithCursor is the same as format when there is no cursorOffset,
+    // other than also returning the offset.
+    response = await formatWithCursor(msg.body, {
+      ...msg.config,
+      plugins,
     });
   } catch (e: any) {
-    if (e.name === "UndefinedParserError") {
-      exit(10);
-    }
-    stdout.printf("%s\n", e.message);
-    exit(1);
+    sendError(e);
+    return;
   }
 
-  const outputMsg = {
+  send({
+    name: "result",
+    body: response.formatted,
+    cursorOffset: response.cursorOffset,
+  });
+}
+
+// https://github.com/prettier/prettier/blob/main/src/utils/infer-parser.js
+function inferParser(filepath: string): string | null {
+  const basename = filepath.split(/[\\/]/).pop()!.toLowerCase();
+  const languages = plugins.flatMap((plugin: any) => plugin.languages ?? []);
+  const language =
+    languages.find(({ filenames }: any) =>
+      filenames?.some((name: string) => name.toLowerCase() === basename),
+    ) ??
+    languages.find(({ extensions }: any) =>
+      extensions?.some((extension: string) => basename.endsWith(extension)),
+    );
+  return language?.parsers[0] ?? null;
+}
+
+function handleInferParser(msg: any) {
+  send({
+    name: "result",
+    body: inferParser(msg.body) ?? "",
+  });
+}
+
+async function handleSupportInfo() {
+  const info = await getSupportInfo({ plugins } as any);
+  send({
+    name: "result",
+    body: JSON.stringify(info),
+  });
+}
+
+// Each config is evaluated in a fresh instance, which the host discards after,
+// since config code may change global state.
+async function handleEvalConfig(msg: any) {
+  let config: any;
+  try {
+    config = await evalConfig(msg.path, msg.body, msg.moduleType);
+  } catch (e: any) {
+    sendError(e instanceof Error ? e : new Error(String(e)));
+    return;
+  }
+  send({
     name: "result",
-    body: response,
-  };
-  const outputStr = JSON.stringify(outputMsg);
-  stderr.printf("%s\n", outputStr);
+    body: JSON.stringify(config ?? null) ?? "null",
+  });
+}
+
+// Requests are handled in a loop until stdin is closed so the host can reuse
+// this instance without paying startup cost for every file.
+async function run() {
+  while (true) {
+    const inputStr = stdin.getline();
+    if (inputStr === null) {
+      break;
+    }
+    const inputMsg = JSON.parse(inputStr);
+    switch (inputMsg.name) {
+      case "format":
+        await handleFormat(inputMsg);
+        break;
+      case "infer-parser":
+        handleInferParser(inputMsg);
+        break;
+      case "support-info":
+        await handleSupportInfo();
+        break;
+      case "eval-config":
+        await handleEvalConfig(inputMsg);
+        break;
+      default:
+        sendError(new Error(`Unknown message "${inputMsg.name}"`));
+    }
+  }
 }
 
 await run();
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/wasilibs/go-prettier/v3/internal/runner"
+)
+
+const daemonUsage = `
+Usage: prettier daemon [options]
+
+Run a daemon that keeps Prettier loaded between invocations. While it is running,
+formatting stdin, as editors do on save, is handled by the daemon to avoid startup
+cost. Files are always formatted in-process. It shuts down when idle or stopped.
+Each version of Prettier has its own daemon.
+
+Options:
+
+  --idle-timeout <duration>
+                           How long to wait for a request before shutting down.
+                           Defaults to 15m.
+  --stop                   Stop the running daemon.
+  --log-level <silent|error|warn|log|debug>
+                           What level of logs to report.
+                           Defaults to log.
+`
+
+// runDaemon runs the daemon subcommand with its arguments, returning the exit code.
+func runDaemon(argv []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), strings.TrimSpace(daemonUsage))
+	}
+
+	idleTimeout := fs.Duration("idle-timeout", runner.DefaultDaemonIdleTimeout, "How long to wait for a request before shutting down.")
+	stop := fs.Bool("stop", false, "Stop the running daemon.")
+	levelFlg := fs.String("log-level", "log", "What level of logs to report.")
+
+	_ = fs.Parse(argv)
+
+	level, ok := parseLogLevel(*levelFlg)
+	if !ok {
+		printInvalidEnumFlagValue("log-level", *levelFlg, false, "debug", "error", "log", "silent", "warn")
+		return 1
+	}
+	// Messages logged while formatting are sent to the client.
+	slog.SetDefault(slog.New(runner.NewDaemonLogHandler(handler{level: level})))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	socketPath := runner.DaemonSocketPath()
+
+	if *stop {
+		if !runner.StopDaemon(ctx, socketPath) {
+			slog.Warn("No daemon is running.")
+		}
+		return 0
+	}
+
+	r := runner.NewRunner()
+	defer func() { _ = r.Close(ctx) }()
+
+	if err := r.ServeDaemon(ctx, socketPath, *idleTimeout); err != nil {
+		if errors.Is(err, runner.ErrDaemonRunning) {
+			slog.Info("A daemon is already running.")
+			return 0
+		}
+		slog.Error(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -5,38 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"strconv"
 	"strings"
-)
-
-const (
-	reset = "\033[0m"
 
-	black        = 30
-	red          = 31
-	green        = 32
-	yellow       = 33
-	blue         = 34
-	magenta      = 35
-	cyan         = 36
-	lightGray    = 37
-	darkGray     = 90
-	lightRed     = 91
-	lightGreen   = 92
-	lightYellow  = 93
-	lightBlue    = 94
-	lightMagenta = 95
-	lightCyan    = 96
-	white        = 97
+	"github.com/wasilibs/go-prettier/v3/internal/color"
 )
 
-func colorize(colorCode int, v string, noColor bool) string {
-	if noColor {
-		return v
-	}
-	return fmt.Sprintf("\033[%sm%s%s", strconv.Itoa(colorCode), v, reset)
-}
-
 type handler struct {
 	level   slog.Level
 	noColor bool
@@ -51,24 +24,27 @@ func (h handler) Enabled(_ context.Context, l slog.Level) bool {
 
 // Handle implements slog.Handler.
 func (h handler) Handle(_ context.Context, r slog.Record) error {
-	var color int
+	var c int
 	switch r.Level {
 	case slog.LevelDebug:
-		color = blue
+		c = color.Blue
 	case slog.LevelInfo:
 		// Default
 	case slog.LevelWarn:
-		color = yellow
+		c = color.Yellow
 	case slog.LevelError:
-		color = red
+		c = color.Red
 	}
 
 	level := strings.ToLower(r.Level.String())
-	if color != 0 {
-		level = colorize(color, level, h.noColor)
+	if c != 0 {
+		level = color.Colorize(c, level, h.noColor)
 	}
 
-	fmt.Fprintf(os.Stderr, "[%s] %s\n", level, r.Message)
+	// Like upstream, prefix every line of multi-line messages such as code frames.
+	for line := range strings.SplitSeq(r.Message, "\n") {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", level, line)
+	}
 
 	return nil
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/wasilibs/go-prettier/v3/internal/lsp"
+	"github.com/wasilibs/go-prettier/v3/internal/runner"
+)
+
+const lspUsage = `
+Usage: prettier lsp [options]
+
+Run a language server over stdio that formats documents with Prettier. Config and
+ignore files are resolved for each document like for the CLI.
+
+Options:
+
+  --config <path>          Path to a Prettier configuration file (.prettierrc, package.json, prettier.config.js).
+  --no-config              Do not look for a configuration file.
+  --no-editorconfig        Don't take .editorconfig into account when parsing configuration.
+  --ignore-path <path>     Path to a file with patterns describing files to ignore.
+                           Multiple values are accepted.
+                           Defaults to [.gitignore, .prettierignore].
+  --with-node-modules      Process files inside 'node_modules' directory.
+  --log-level <silent|error|warn|log|debug>
+                           What level of logs to report.
+                           Defaults to log.
+`
+
+// runLSP runs the lsp subcommand with its arguments, returning the exit code.
+func runLSP(argv []string) int {
+	var args runner.RunArgs
+
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), strings.TrimSpace(lspUsage))
+	}
+
+	var ignorePaths sliceFlag
+	fs.Var(&ignorePaths, "ignore-path", "Path to a file with patterns describing files to ignore.")
+	fs.StringVar(&args.Config, "config", "", "Path to a Prettier configuration file.")
+	fs.BoolVar(&args.NoConfig, "no-config", false, "Do not look for a configuration file.")
+	fs.BoolVar(&args.NoEditorConfig, "no-editorconfig", false, "Don't take .editorconfig into account when parsing configuration.")
+	fs.BoolVar(&args.WithNodeModules, "with-node-modules", false, "Process files inside 'node_modules' directory.")
+	levelFlg := fs.String("log-level", "log", "What level of logs to report.")
+
+	_ = fs.Parse(argv)
+
+	level, ok := parseLogLevel(*levelFlg)
+	if !ok {
+		printInvalidEnumFlagValue("log-level", *levelFlg, true, "debug", "error", "log", "silent", "warn")
+		return 1
+	}
+	// Clients show stderr in a log rather than a terminal so never colorize.
+	slog.SetDefault(slog.New(handler{level: level, noColor: true}))
+
+	if len(ignorePaths) == 0 {
+		ignorePaths = append(ignorePaths, ".gitignore", ".prettierignore")
+	}
+	args.IgnorePaths = ignorePaths
+	args.Cwd = "."
+
+	ctx := context.Background()
+
+	r := runner.NewRunner()
+	defer func() { _ = r.Close(ctx) }()
+
+	if err := lsp.NewServer(r, args).Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	return 0
+}
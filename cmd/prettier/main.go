@@ -7,13 +7,20 @@ import (
 	"log/slog"
 	"math"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 
+	"github.com/wasilibs/go-prettier/v3/internal/color"
 	"github.com/wasilibs/go-prettier/v3/internal/runner"
 )
 
 const usage = `
 Usage: prettier [options] [file/dir/glob ...]
+       prettier lsp [options]
+       prettier daemon [options]
 
 By default, output is written to stdout.
 
@@ -21,11 +28,85 @@ Output options:
 
   -c, --check              Check if the given files are formatted, print a human-friendly summary
                            message and paths to unformatted files (see also --list-different).
+  --diff                   Print a unified diff of the changes Prettier would make to each unformatted file.
+  --diff-context <int>     Number of unchanged lines to show around each change in a diff.
+                           Defaults to 3.
+  -l, --list-different     Print the names of files that are different from Prettier's formatting (see also --check).
   -w, --write              Edit files in-place. (Beware!)
+  --watch                  Keep running and format files again when they change, usually with --write.
+
+Format options:
+
+  --arrow-parens <always|avoid>
+                           Include parentheses around a sole arrow function parameter.
+                           Defaults to always.
+  --bracket-same-line      Put > of opening tags on the last line instead of on a new line.
+                           Defaults to false.
+  --no-bracket-spacing     Do not print spaces between brackets.
+  --embedded-language-formatting <auto|off>
+                           Control how Prettier formats quoted code embedded in the file.
+                           Defaults to auto.
+  --end-of-line <lf|crlf|cr|auto>
+                           Which end of line characters to apply.
+                           Defaults to lf.
+  --experimental-operator-position <start|end>
+                           Where to print operators when binary expressions wrap lines.
+                           Defaults to end.
+  --experimental-ternaries
+                           Use curious ternaries, with the question mark after the condition.
+                           Defaults to false.
+  --html-whitespace-sensitivity <css|strict|ignore>
+                           How to handle whitespaces in HTML.
+                           Defaults to css.
+  --jsx-single-quote       Use single quotes in JSX.
+                           Defaults to false.
+  --object-wrap <preserve|collapse>
+                           How to wrap object literals.
+                           Defaults to preserve.
+  --parser <command>       Which parser to use. Also allows formatting stdin without --stdin-filepath.
+  --print-width <int>      The line length where Prettier will try wrap.
+                           Defaults to 80.
+  --prose-wrap <always|never|preserve>
+                           How to wrap prose.
+                           Defaults to preserve.
+  --quote-props <as-needed|consistent|preserve>
+                           Change when properties in objects are quoted.
+                           Defaults to as-needed.
+  --no-semi                Do not print semicolons, except at the beginning of lines which may need them.
+  --single-attribute-per-line
+                           Enforce single attribute per line in HTML, Vue and JSX.
+                           Defaults to false.
+  --single-quote           Use single quotes instead of double quotes.
+                           Defaults to false.
+  --tab-width <int>        Number of spaces per indentation level.
+                           Defaults to 2.
+  --trailing-comma <all|es5|none>
+                           Print trailing commas wherever possible when multi-line.
+                           Defaults to all.
+  --use-tabs               Indent with tabs instead of spaces.
+                           Defaults to false.
+  --vue-indent-script-and-style
+                           Indent script and style tags in Vue files.
+                           Defaults to false.
+
+Editor options:
+
+  --cursor-offset <int>    Print (to stderr) where a cursor at the given position would move to after formatting.
+                           This option cannot be used with --range-start and --range-end.
+                           Defaults to -1.
+  --range-end <int>        Format code ending at a given character offset (exclusive).
+                           The range will extend forwards to the end of the selected statement.
+                           Defaults to Infinity.
+  --range-start <int>      Format code starting at a given character offset.
+                           The range will extend backwards to the start of the first line containing the selected statement.
+                           Defaults to 0.
 
 Config options:
 
   --config <path>          Path to a Prettier configuration file (.prettierrc, package.json, prettier.config.js).
+  --config-precedence <cli-override|file-override|prefer-file>
+                           Define in which order config files and CLI options should be evaluated.
+                           Defaults to cli-override.
   --no-config              Do not look for a configuration file.
   --no-editorconfig        Don't take .editorconfig into account when parsing configuration.
   --ignore-path <path>     Path to a file with patterns describing files to ignore.
@@ -35,17 +116,51 @@ Config options:
 
 Other options:
 
+  --cache                  Only format changed files. Cannot use with --stdin-filepath.
+                           Defaults to false.
+  --cache-location <path>  Path to the cache file.
+  --cache-strategy <metadata|content>
+                           Strategy for the cache to use for detecting changed files.
+  --changed-since <rev>    Only format files that differ from the given git revision, including untracked files.
+                           Without patterns, files in the current directory are checked.
+  --file-info <path>       Extract the following info (as JSON) for a given file path. Reported fields:
+                           * ignored (boolean) - true if file path is filtered by --ignore-path
+                           * inferredParser (string | null) - name of parser inferred from file path
+  --find-config-path <path>
+                           Find and print the path to a configuration file for the given input file.
+  --print-config <path>    Print the resolved configuration for the given input file.
+  --staged                 Only format files with changes staged in the git index. With --write, the staged
+                           content is formatted and staged again, for use in a pre-commit hook. Without
+                           patterns, files in the current directory are checked.
+  --reporter <json|sarif|github>
+                           Print a machine-readable report of the result for each file instead of
+                           human-readable output.
   --no-color               Do not colorize error messages.
+  --no-daemon              Do not use a running daemon (see prettier daemon) to format stdin.
   --no-error-on-unmatched-pattern
                            Prevent errors when pattern is unmatched.
   -h, --help               Show CLI usage
   -u, --ignore-unknown     Ignore unknown files.
+  --insert-pragma          Insert @format pragma into file's first docblock comment.
+                           Defaults to false.
   --log-level <silent|error|warn|log|debug>
                            What level of logs to report.
                            Defaults to log.
+  --require-pragma         Require either '@prettier' or '@format' to be present in the file's first docblock comment
+                           in order for it to be formatted.
+                           Defaults to false.
 `
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lsp":
+			os.Exit(runLSP(os.Args[2:]))
+		case "daemon":
+			os.Exit(runDaemon(os.Args[2:]))
+		}
+	}
+
 	var args runner.RunArgs
 
 	flag.Usage = func() {
@@ -54,13 +169,19 @@ func main() {
 
 	flag.BoolVar(&args.Check, "check", false, "Check if the given files are formatted, print a human-friendly summary message and paths to unformatted files")
 	flag.BoolVar(&args.Check, "c", false, "Check if the given files are formatted, print a human-friendly summary message and paths to unformatted files")
+	flag.BoolVar(&args.ListDifferent, "list-different", false, "Print the names of files that are different from Prettier's formatting")
+	flag.BoolVar(&args.ListDifferent, "l", false, "Print the names of files that are different from Prettier's formatting")
+	flag.BoolVar(&args.Diff, "diff", false, "Print a unified diff of the changes Prettier would make to each unformatted file.")
+	flag.IntVar(&args.DiffContext, "diff-context", runner.DefaultDiffContext, "Number of unchanged lines to show around each change in a diff.")
 	flag.BoolVar(&args.Write, "write", false, "Edit files in-place. (Beware!)")
 	flag.BoolVar(&args.Write, "w", false, "Edit files in-place. (Beware!)")
+	flag.BoolVar(&args.Watch, "watch", false, "Keep running and format files again when they change, usually with --write.")
 
 	var ignorePaths sliceFlag
 	flag.Var(&ignorePaths, "ignore-path", "Path to a file with patterns describing files to ignore.\nMultiple values are accepted.\nDefaults to [.gitignore, .prettierignore].")
 
-	flag.StringVar(&args.Config, "config", "", "Path to a Prettier configuration file (.prettierrc, .prettierrc.yaml, .prettierrc.toml).")
+	flag.StringVar(&args.Config, "config", "", "Path to a Prettier configuration file (.prettierrc, package.json, .prettierrc.json5, .prettierrc.yaml, .prettierrc.toml, prettier.config.js).")
+	flag.StringVar(&args.ConfigPrecedence, "config-precedence", runner.ConfigPrecedenceCLIOverride, "<cli-override|file-override|prefer-file>\nDefine in which order config files and CLI options should be evaluated.")
 	flag.BoolVar(&args.NoConfig, "no-config", false, "Do not look for a configuration file.")
 	flag.BoolVar(&args.NoEditorConfig, "no-editorconfig", false, "Don't take .editorconfig into account when parsing configuration.")
 	flag.BoolVar(&args.NoErrorOnUnmatchedPattern, "no-error-on-unmatched-pattern", false, "Prevent errors when pattern is unmatched.")
@@ -68,46 +189,171 @@ func main() {
 	flag.BoolVar(&args.IgnoreUnknown, "ignore-unknown", false, "Ignore unknown files.")
 	flag.BoolVar(&args.IgnoreUnknown, "u", false, "Ignore unknown files.")
 
+	args.Options = map[string]any{}
+	registerFormatOptions(args.Options)
+
+	flag.StringVar(&args.Parser, "parser", "", "Which parser to use.")
+
 	flag.StringVar(&args.StdinFilepath, "stdin-filepath", "", "Format stdin and write the result to stdout, using this path to infer the parser.")
 
+	flag.StringVar(&args.FileInfo, "file-info", "", "Extract the following info (as JSON) for a given file path.")
+	flag.StringVar(&args.FindConfigPath, "find-config-path", "", "Find and print the path to a configuration file for the given input file.")
+
+	flag.StringVar(&args.PrintConfig, "print-config", "", "Print the resolved configuration for the given input file.")
+
+	flag.BoolVar(&args.Cache, "cache", false, "Only format changed files. Cannot use with --stdin-filepath.")
+	flag.StringVar(&args.CacheLocation, "cache-location", "", "Path to the cache file.")
+	flag.StringVar(&args.CacheStrategy, "cache-strategy", "", "<metadata|content>\nStrategy for the cache to use for detecting changed files.")
+
+	flag.StringVar(&args.ChangedSince, "changed-since", "", "Only format files that differ from the given git revision, including untracked files.")
+	flag.BoolVar(&args.Staged, "staged", false, "Only format files with changes staged in the git index. With --write, the staged content is formatted and staged again.")
+
+	flag.StringVar(&args.Reporter, "reporter", "", "<json|sarif|github>\nPrint a machine-readable report of the result for each file instead of human-readable output.")
+
 	noColor := flag.Bool("no-color", false, "Do not colorize error messages.")
+	noDaemon := flag.Bool("no-daemon", false, "Do not use a running daemon to format stdin.")
 	levelFlg := flag.String("log-level", "log", "<silent|error|warn|log|debug>\nWhat level of logs to report.\nDefaults to log.")
 
 	flag.Parse()
 
-	var level slog.Level
-	switch strings.ToLower(*levelFlg) {
-	case "silent":
-		level = slog.Level(math.MaxInt)
-	case "error":
-		level = slog.LevelError
-	case "warn":
-		level = slog.LevelWarn
-	case "log":
-		level = slog.LevelInfo
-	case "debug":
-		level = slog.LevelDebug
-	default:
+	level, ok := parseLogLevel(*levelFlg)
+	if !ok {
 		printInvalidEnumFlagValue("log-level", *levelFlg, *noColor, "debug", "error", "log", "silent", "warn")
 		os.Exit(1)
 	}
 	slog.SetDefault(slog.New(handler{level: level, noColor: *noColor}))
 
+	if !validateFormatOptions(args.Options, *noColor) {
+		os.Exit(1)
+	}
+
+	switch args.ConfigPrecedence {
+	case runner.ConfigPrecedenceCLIOverride, runner.ConfigPrecedenceFileOverride, runner.ConfigPrecedencePreferFile:
+	default:
+		printInvalidEnumFlagValue("config-precedence", args.ConfigPrecedence, *noColor, runner.ConfigPrecedenceCLIOverride, runner.ConfigPrecedenceFileOverride, runner.ConfigPrecedencePreferFile)
+		os.Exit(1)
+	}
+
+	switch args.CacheStrategy {
+	case "", runner.CacheStrategyMetadata, runner.CacheStrategyContent:
+	default:
+		printInvalidEnumFlagValue("cache-strategy", args.CacheStrategy, *noColor, runner.CacheStrategyContent, runner.CacheStrategyMetadata)
+		os.Exit(1)
+	}
+
+	if args.DiffContext < 0 {
+		slog.Error(fmt.Sprintf(`Invalid %s value. Expected a non-negative integer, but received %s.`, color.Colorize(color.Red, "--diff-context", *noColor), color.Colorize(color.Red, strconv.Itoa(args.DiffContext), *noColor)))
+		os.Exit(1)
+	}
+
+	switch args.Reporter {
+	case "", runner.ReporterJSON, runner.ReporterSARIF, runner.ReporterGitHub:
+	default:
+		printInvalidEnumFlagValue("reporter", args.Reporter, *noColor, runner.ReporterGitHub, runner.ReporterJSON, runner.ReporterSARIF)
+		os.Exit(1)
+	}
+
+	// --no-color is for messages on stderr while diffs are written to stdout.
+	args.DiffColor = color.Enabled(os.Stdout)
 	args.Cwd = "."
 	args.Patterns = flag.Args()
+	// Like upstream, read stdin when there are no patterns and it is not a terminal.
+	// --staged and --changed-since format the repository without patterns instead,
+	// as git hooks are often run with stdin that is not a terminal.
+	if len(args.Patterns) == 0 && !args.Staged && args.ChangedSince == "" {
+		if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+			args.Stdin = true
+		}
+	}
 
 	if len(ignorePaths) == 0 {
 		ignorePaths = append(ignorePaths, ".gitignore", ".prettierignore")
 	}
 	args.IgnorePaths = ignorePaths
 
-	r := runner.NewRunner()
-	if err := r.Run(context.Background(), args); err != nil {
+	if args.ChangedSince != "" && args.Staged {
+		slog.Error("Cannot use --changed-since and --staged together.")
+		os.Exit(1)
+	}
+
+	if args.Watch && (args.ChangedSince != "" || args.Staged) {
+		slog.Error("Cannot use --watch with --changed-since or --staged.")
+		os.Exit(1)
+	}
+
+	if args.Watch && len(args.Patterns) == 0 {
+		slog.Error("--watch requires file patterns to watch.")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	stop := func() {}
+	if args.Watch {
+		// Watching ends when interrupted.
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	}
+
+	// Prefer a daemon when one is running to skip startup, which is mostly creating
+	// a runner as it compiles the Wasm module. Only stdin is formatted by the daemon.
+	var r *runner.Runner
+
+	if args.Parser != "" {
+		parsers, ok := []string(nil), false
+		if !*noDaemon {
+			parsers, ok = runner.DaemonParsers(ctx)
+		}
+		if !ok {
+			r = runner.NewRunner()
+			var err error
+			parsers, err = r.Parsers(ctx)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+		}
+		if !slices.Contains(parsers, args.Parser) {
+			printInvalidEnumFlagValue("parser", args.Parser, *noColor, parsers...)
+			os.Exit(1)
+		}
+	}
+
+	// A runner was only created if no daemon is running.
+	if r == nil {
+		if !*noDaemon {
+			if ok, err := runner.RunWithDaemon(ctx, args); ok {
+				if err != nil {
+					os.Exit(1)
+				}
+				return
+			}
+		}
+		r = runner.NewRunner()
+	}
+
+	err := r.Run(ctx, args)
+	stop()
+	if err != nil {
 		// Runner handles logging so we just need to set error code.
 		os.Exit(1)
 	}
 }
 
+func parseLogLevel(s string) (slog.Level, bool) {
+	switch strings.ToLower(s) {
+	case "silent":
+		return slog.Level(math.MaxInt), true
+	case "error":
+		return slog.LevelError, true
+	case "warn":
+		return slog.LevelWarn, true
+	case "log":
+		return slog.LevelInfo, true
+	case "debug":
+		return slog.LevelDebug, true
+	}
+	return 0, false
+}
+
 type sliceFlag []string
 
 func (f *sliceFlag) String() string {
@@ -120,8 +366,8 @@ func (f *sliceFlag) Set(s string) error {
 }
 
 func printInvalidEnumFlagValue(flag string, value string, noColor bool, choices ...string) {
-	slog.Error(fmt.Sprintf(`Invalid %s value. Expected %s, but received %s.`, colorize(red, "--"+flag, noColor), colorize(blue, "one of the following values", noColor), colorize(red, fmt.Sprintf(`"%s"`, value), noColor)))
+	slog.Error(fmt.Sprintf(`Invalid %s value. Expected %s, but received %s.`, color.Colorize(color.Red, "--"+flag, noColor), color.Colorize(color.Blue, "one of the following values", noColor), color.Colorize(color.Red, fmt.Sprintf(`"%s"`, value), noColor)))
 	for _, choice := range choices {
-		slog.Error("- " + colorize(blue, fmt.Sprintf(`"%s"`, choice), noColor))
+		slog.Error("- " + color.Colorize(color.Blue, fmt.Sprintf(`"%s"`, choice), noColor))
 	}
 }
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+type optionKind byte
+
+const (
+	optionKindBool optionKind = iota
+	// optionKindNegatedBool is a --no-* flag which sets the option to false.
+	optionKindNegatedBool
+	optionKindInt
+	optionKindChoice
+)
+
+type formatOption struct {
+	flag    string
+	option  string
+	kind    optionKind
+	choices []string
+	usage   string
+}
+
+// https://github.com/prettier/prettier/blob/main/src/main/core-options.evaluate.js
+// https://github.com/prettier/prettier/blob/main/src/language-js/options.js
+var formatOptions = []formatOption{
+	{
+		flag:    "arrow-parens",
+		option:  "arrowParens",
+		kind:    optionKindChoice,
+		choices: []string{"always", "avoid"},
+		usage:   "Include parentheses around a sole arrow function parameter.",
+	},
+	{
+		flag:   "bracket-same-line",
+		option: "bracketSameLine",
+		kind:   optionKindBool,
+		usage:  "Put > of opening tags on the last line instead of on a new line.",
+	},
+	{
+		flag:   "no-bracket-spacing",
+		option: "bracketSpacing",
+		kind:   optionKindNegatedBool,
+		usage:  "Do not print spaces between brackets.",
+	},
+	{
+		flag:   "cursor-offset",
+		option: "cursorOffset",
+		kind:   optionKindInt,
+		usage:  "Print (to stderr) where a cursor at the given position would move to after formatting.",
+	},
+	{
+		flag:    "embedded-language-formatting",
+		option:  "embeddedLanguageFormatting",
+		kind:    optionKindChoice,
+		choices: []string{"auto", "off"},
+		usage:   "Control how Prettier formats quoted code embedded in the file.",
+	},
+	{
+		flag:    "end-of-line",
+		option:  "endOfLine",
+		kind:    optionKindChoice,
+		choices: []string{"lf", "crlf", "cr", "auto"},
+		usage:   "Which end of line characters to apply.",
+	},
+	{
+		flag:    "experimental-operator-position",
+		option:  "experimentalOperatorPosition",
+		kind:    optionKindChoice,
+		choices: []string{"start", "end"},
+		usage:   "Where to print operators when binary expressions wrap lines.",
+	},
+	{
+		flag:   "experimental-ternaries",
+		option: "experimentalTernaries",
+		kind:   optionKindBool,
+		usage:  "Use curious ternaries, with the question mark after the condition.",
+	},
+	{
+		flag:    "html-whitespace-sensitivity",
+		option:  "htmlWhitespaceSensitivity",
+		kind:    optionKindChoice,
+		choices: []string{"css", "strict", "ignore"},
+		usage:   "How to handle whitespaces in HTML.",
+	},
+	{
+		flag:   "insert-pragma",
+		option: "insertPragma",
+		kind:   optionKindBool,
+		usage:  "Insert @format pragma into file's first docblock comment.",
+	},
+	{
+		flag:   "jsx-single-quote",
+		option: "jsxSingleQuote",
+		kind:   optionKindBool,
+		usage:  "Use single quotes in JSX.",
+	},
+	{
+		flag:    "object-wrap",
+		option:  "objectWrap",
+		kind:    optionKindChoice,
+		choices: []string{"preserve", "collapse"},
+		usage:   "How to wrap object literals.",
+	},
+	{
+		flag:   "print-width",
+		option: "printWidth",
+		kind:   optionKindInt,
+		usage:  "The line length where Prettier will try wrap.",
+	},
+	{
+		flag:    "prose-wrap",
+		option:  "proseWrap",
+		kind:    optionKindChoice,
+		choices: []string{"always", "never", "preserve"},
+		usage:   "How to wrap prose.",
+	},
+	{
+		flag:    "quote-props",
+		option:  "quoteProps",
+		kind:    optionKindChoice,
+		choices: []string{"as-needed", "consistent", "preserve"},
+		usage:   "Change when properties in objects are quoted.",
+	},
+	{
+		flag:   "no-semi",
+		option: "semi",
+		kind:   optionKindNegatedBool,
+		usage:  "Do not print semicolons, except at the beginning of lines which may need them.",
+	},
+	{
+		flag:   "range-end",
+		option: "rangeEnd",
+		kind:   optionKindInt,
+		usage:  "Format code ending at a given character offset (exclusive).",
+	},
+	{
+		flag:   "range-start",
+		option: "rangeStart",
+		kind:   optionKindInt,
+		usage:  "Format code starting at a given character offset.",
+	},
+	{
+		flag:   "require-pragma",
+		option: "requirePragma",
+		kind:   optionKindBool,
+		usage:  "Require either '@prettier' or '@format' to be present in the file's first docblock comment in order for it to be formatted.",
+	},
+	{
+		flag:   "single-attribute-per-line",
+		option: "singleAttributePerLine",
+		kind:   optionKindBool,
+		usage:  "Enforce single attribute per line in HTML, Vue and JSX.",
+	},
+	{
+		flag:   "single-quote",
+		option: "singleQuote",
+		kind:   optionKindBool,
+		usage:  "Use single quotes instead of double quotes.",
+	},
+	{
+		flag:   "tab-width",
+		option: "tabWidth",
+		kind:   optionKindInt,
+		usage:  "Number of spaces per indentation level.",
+	},
+	{
+		flag:    "trailing-comma",
+		option:  "trailingComma",
+		kind:    optionKindChoice,
+		choices: []string{"all", "es5", "none"},
+		usage:   "Print trailing commas wherever possible when multi-line.",
+	},
+	{
+		flag:   "use-tabs",
+		option: "useTabs",
+		kind:   optionKindBool,
+		usage:  "Indent with tabs instead of spaces.",
+	},
+	{
+		flag:   "vue-indent-script-and-style",
+		option: "vueIndentScriptAndStyle",
+		kind:   optionKindBool,
+		usage:  "Indent script and style tags in Vue files.",
+	},
+}
+
+// optionFlag records a format option in opts only when the flag is passed, so
+// that unset flags do not override config files.
+type optionFlag struct {
+	opt  formatOption
+	opts map[string]any
+}
+
+var _ flag.Value = optionFlag{}
+
+// String implements flag.Value.
+func (f optionFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+	if v, ok := f.opts[f.opt.option]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// Set implements flag.Value.
+func (f optionFlag) Set(s string) error {
+	switch f.opt.kind {
+	case optionKindBool, optionKindNegatedBool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean value %q", s)
+		}
+		f.opts[f.opt.option] = v != (f.opt.kind == optionKindNegatedBool)
+	case optionKindInt:
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer value %q", s)
+		}
+		f.opts[f.opt.option] = v
+	case optionKindChoice:
+		f.opts[f.opt.option] = s
+	}
+	return nil
+}
+
+// IsBoolFlag allows boolean options to be passed without a value.
+func (f optionFlag) IsBoolFlag() bool {
+	return f.opt.kind == optionKindBool || f.opt.kind == optionKindNegatedBool
+}
+
+func registerFormatOptions(opts map[string]any) {
+	for _, opt := range formatOptions {
+		flag.Var(optionFlag{opt: opt, opts: opts}, opt.flag, opt.usage)
+	}
+}
+
+// validateFormatOptions checks choice options, printing an error and returning false
+// if any are invalid.
+func validateFormatOptions(opts map[string]any, noColor bool) bool {
+	for _, opt := range formatOptions {
+		if opt.kind != optionKindChoice {
+			continue
+		}
+		v, ok := opts[opt.option].(string)
+		if !ok || slices.Contains(opt.choices, v) {
+			continue
+		}
+		printInvalidEnumFlagValue(opt.flag, v, noColor, slices.Sorted(slices.Values(opt.choices))...)
+		return false
+	}
+	return true
+}
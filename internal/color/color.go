@@ -0,0 +1,54 @@
+// Package color provides ANSI coloring of terminal output.
+package color
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	reset = "\033[0m"
+
+	Bold = 1
+
+	Black        = 30
+	Red          = 31
+	Green        = 32
+	Yellow       = 33
+	Blue         = 34
+	Magenta      = 35
+	Cyan         = 36
+	LightGray    = 37
+	DarkGray     = 90
+	LightRed     = 91
+	LightGreen   = 92
+	LightYellow  = 93
+	LightBlue    = 94
+	LightMagenta = 95
+	LightCyan    = 96
+	White        = 97
+)
+
+// Enabled returns whether output to f should be colorized, which is when it is a
+// terminal. Like other tools, NO_COLOR disables color and FORCE_COLOR enables it
+// even when f is not a terminal.
+// https://no-color.org/
+func Enabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		return v != "0" && v != "false"
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Colorize wraps v in the escape codes for colorCode unless noColor is set.
+func Colorize(colorCode int, v string, noColor bool) string {
+	if noColor {
+		return v
+	}
+	return fmt.Sprintf("\033[%sm%s%s", strconv.Itoa(colorCode), v, reset)
+}
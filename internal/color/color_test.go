@@ -0,0 +1,39 @@
+package color
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnabled(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	tests := []struct {
+		name       string
+		noColor    string
+		forceColor string
+		exp        bool
+	}{
+		{name: "not a terminal"},
+		{name: "force color", forceColor: "1", exp: true},
+		{name: "force color disabled", forceColor: "0"},
+		{name: "no color", noColor: "1", forceColor: "1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			if tc.forceColor == "" {
+				t.Setenv("FORCE_COLOR", "")
+				os.Unsetenv("FORCE_COLOR")
+			} else {
+				t.Setenv("FORCE_COLOR", tc.forceColor)
+			}
+			require.Equal(t, tc.exp, Enabled(w))
+		})
+	}
+}
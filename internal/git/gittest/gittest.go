@@ -0,0 +1,59 @@
+// Package gittest creates repositories with the git CLI for tests, which is the
+// reference for the formats read by package git.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Env returns the environment to run git with, isolated from the user's and
+// system configuration and with a fixed identity for commits.
+func Env() []string {
+	return append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test",
+		"GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test",
+		"GIT_COMMITTER_EMAIL=test@example.com",
+	)
+}
+
+// NewRepo creates a repository in a temporary directory, passing extraInitArgs to
+// git init, and returns it with a function to run git in it that returns the
+// output with surrounding whitespace trimmed. The test is skipped if git is not
+// installed.
+func NewRepo(t testing.TB, extraInitArgs ...string) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = Env()
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	run(append([]string{"init", "-q", "-b", "main"}, extraInitArgs...)...)
+	return dir, run
+}
+
+// WriteFile writes content to the file at the slash-separated path name in dir,
+// creating parent directories.
+func WriteFile(t testing.TB, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
@@ -0,0 +1,398 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// https://git-scm.com/docs/index-format
+
+// Index entry flags.
+const (
+	indexFlagExtended = 0x4000
+	indexFlagStage    = 0x3000
+	indexNameMask     = 0xfff
+)
+
+// IndexEntry is a file in the index.
+type IndexEntry struct {
+	// Path is slash-separated and relative to the repository root.
+	Path string
+	Mode uint32
+	Hash Hash
+	// Stage is non-zero for files with merge conflicts.
+	Stage int
+
+	// Stat data of the file when it was last added or refreshed.
+	CTime time.Time
+	MTime time.Time
+	Dev   uint32
+	Ino   uint32
+	UID   uint32
+	GID   uint32
+	Size  uint32
+
+	flags         uint16
+	extendedFlags uint16
+}
+
+// Index is the staging area of a repository.
+type Index struct {
+	Version int
+	Entries []IndexEntry
+
+	extensions []indexExtension
+
+	// modTime is when the index file was written, for detecting racily clean
+	// entries.
+	modTime time.Time
+}
+
+// ReadIndex reads the index of the repository. An empty index is returned if the
+// repository has none yet.
+func (r *Repo) ReadIndex() (*Index, error) {
+	p := r.indexPath()
+	b, err := os.ReadFile(p) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Index{Version: 2}, nil
+		}
+		return nil, fmt.Errorf("git: reading index: %w", err)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("git: reading index: %w", err)
+	}
+
+	idx, err := parseIndex(b, r.hashSize())
+	if err != nil {
+		return nil, err
+	}
+	idx.modTime = fi.ModTime()
+	return idx, nil
+}
+
+// indexPath returns the path of the index. Git sets GIT_INDEX_FILE for hooks when
+// committing with a temporary index, such as for git commit -a.
+func (r *Repo) indexPath() string {
+	if p := os.Getenv("GIT_INDEX_FILE"); p != "" {
+		if abs, err := filepath.Abs(p); err == nil {
+			return abs
+		}
+	}
+	return filepath.Join(r.gitDir, "index")
+}
+
+var errInvalidIndex = errors.New("git: invalid index")
+
+func parseIndex(b []byte, hashSize int) (*Index, error) {
+	if len(b) < 12+hashSize || !bytes.Equal(b[:4], []byte("DIRC")) {
+		return nil, errInvalidIndex
+	}
+	idx := &Index{Version: int(binary.BigEndian.Uint32(b[4:8]))}
+	if idx.Version < 2 || idx.Version > 4 {
+		return nil, fmt.Errorf("git: unsupported index version %d", idx.Version)
+	}
+	n := int(binary.BigEndian.Uint32(b[8:12]))
+
+	pos := 12
+	// Extensions and the checksum follow the entries.
+	end := len(b) - hashSize
+	prevPath := ""
+	for range n {
+		start := pos
+		if pos+40+hashSize+2 > end {
+			return nil, errInvalidIndex
+		}
+		u32 := func(i int) uint32 { return binary.BigEndian.Uint32(b[start+i*4:]) }
+		e := IndexEntry{
+			CTime: time.Unix(int64(u32(0)), int64(u32(1))),
+			MTime: time.Unix(int64(u32(2)), int64(u32(3))),
+			Dev:   u32(4),
+			Ino:   u32(5),
+			Mode:  u32(6),
+			UID:   u32(7),
+			GID:   u32(8),
+			Size:  u32(9),
+		}
+		pos += 40
+		e.Hash = Hash(hex.EncodeToString(b[pos : pos+hashSize]))
+		pos += hashSize
+		e.flags = binary.BigEndian.Uint16(b[pos:])
+		pos += 2
+		e.Stage = int(e.flags&indexFlagStage) >> 12
+		if e.flags&indexFlagExtended != 0 && idx.Version >= 3 {
+			if pos+2 > end {
+				return nil, errInvalidIndex
+			}
+			e.extendedFlags = binary.BigEndian.Uint16(b[pos:])
+			pos += 2
+		}
+
+		if idx.Version == 4 {
+			// The path is the previous path with some bytes removed from the end and
+			// the rest appended.
+			strip, m := readOffsetVarint(b[pos:end])
+			if m == 0 || strip > len(prevPath) {
+				return nil, errInvalidIndex
+			}
+			pos += m
+			nul := bytes.IndexByte(b[pos:end], 0)
+			if nul < 0 {
+				return nil, errInvalidIndex
+			}
+			e.Path = prevPath[:len(prevPath)-strip] + string(b[pos:pos+nul])
+			pos += nul + 1
+		} else {
+			nul := bytes.IndexByte(b[pos:end], 0)
+			if nul < 0 {
+				return nil, errInvalidIndex
+			}
+			e.Path = string(b[pos : pos+nul])
+			// Entries are padded with NULs to a multiple of 8 bytes.
+			pos = start + (pos-start+nul+8)&^7
+		}
+		prevPath = e.Path
+
+		idx.Entries = append(idx.Entries, e)
+	}
+
+	for pos+8 <= end {
+		size := int(binary.BigEndian.Uint32(b[pos+4:]))
+		if pos+8+size > end {
+			return nil, errInvalidIndex
+		}
+		idx.extensions = append(idx.extensions, indexExtension{
+			signature: string(b[pos : pos+4]),
+			data:      b[pos+8 : pos+8+size],
+		})
+		pos += 8 + size
+	}
+
+	return idx, nil
+}
+
+type indexExtension struct {
+	signature string
+	data      []byte
+}
+
+// readOffsetVarint reads the variable-length integer used for offsets in packs
+// and path prefixes in version 4 indexes, returning it and the number of bytes
+// read, or 0 if b is truncated.
+func readOffsetVarint(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	c := b[0]
+	v := int(c & 0x7f)
+	i := 1
+	for c&0x80 != 0 {
+		if i >= len(b) {
+			return 0, 0
+		}
+		c = b[i]
+		i++
+		v = ((v + 1) << 7) | int(c&0x7f)
+	}
+	return v, i
+}
+
+// Entry returns the stage 0 entry for path, or nil if there is none.
+func (idx *Index) Entry(path string) *IndexEntry {
+	// Entries are sorted by path, then stage.
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		return idx.Entries[i].Path >= path
+	})
+	if i < len(idx.Entries) && idx.Entries[i].Path == path && idx.Entries[i].Stage == 0 {
+		return &idx.Entries[i]
+	}
+	return nil
+}
+
+// Unchanged returns whether the file described by fi is known to have the same
+// content as e from its stat data alone, without hashing it. Like git, entries
+// modified at or after the index was written are racily clean and never
+// considered unchanged, as the file could have been modified again within the
+// timestamp resolution.
+func (idx *Index) Unchanged(e *IndexEntry, fi os.FileInfo) bool {
+	if !fi.Mode().IsRegular() {
+		return false
+	}
+	if uint32(fi.Size()) != e.Size || !fi.ModTime().Equal(e.MTime) { //nolint:gosec
+		return false
+	}
+	return e.MTime.Before(idx.modTime)
+}
+
+// SetStat records the stat data of the file described by fi after writing it,
+// so that it is known to match e. Only the size and modification time are
+// available portably, so git will still hash the file once to refresh the rest.
+func (e *IndexEntry) SetStat(fi os.FileInfo) {
+	e.Size = uint32(fi.Size()) //nolint:gosec
+	e.MTime = fi.ModTime()
+}
+
+// Invalidate clears the stat data of e so that the file in the working tree is
+// hashed to compare it, for when e no longer matches it.
+func (e *IndexEntry) Invalidate() {
+	// Like git does for racily clean entries.
+	e.Size = 0
+	e.MTime = time.Unix(0, 0)
+}
+
+// ErrIndexLocked is returned by LockIndex when another process is writing the
+// index.
+var ErrIndexLocked = errors.New("git: index is locked")
+
+// LockedIndex is the index of a repository, locked for writing until Commit or
+// Unlock is called.
+type LockedIndex struct {
+	*Index
+
+	repo *Repo
+	lock *os.File
+}
+
+// LockIndex locks the index of the repository, like git does while writing it,
+// and reads it.
+func (r *Repo) LockIndex() (*LockedIndex, error) {
+	p := r.indexPath() + ".lock"
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s exists", ErrIndexLocked, p)
+		}
+		return nil, fmt.Errorf("git: locking index: %w", err)
+	}
+
+	idx, err := r.ReadIndex()
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(p)
+		return nil, err
+	}
+
+	// Extensions are unsupported or describe entries we may change.
+	var exts []indexExtension
+	for _, ext := range idx.extensions {
+		switch ext.signature {
+		case "link":
+			_ = f.Close()
+			_ = os.Remove(p)
+			return nil, errors.New("git: split index is not supported")
+		case "sdir":
+			// Entries for directories outside the sparse checkout can't be updated.
+			_ = f.Close()
+			_ = os.Remove(p)
+			return nil, errors.New("git: sparse index is not supported")
+		case "TREE", "UNTR", "EOIE", "IEOT", "FSMN":
+			// Cached trees, the untracked cache, offsets of entries and fsmonitor
+			// state are optional and recomputed by git.
+		default:
+			exts = append(exts, ext)
+		}
+	}
+	idx.extensions = exts
+
+	return &LockedIndex{Index: idx, repo: r, lock: f}, nil
+}
+
+// Commit writes the index and unlocks it.
+func (l *LockedIndex) Commit() error {
+	b := l.encode(l.repo)
+	if _, err := l.lock.Write(b); err != nil {
+		l.Unlock()
+		return fmt.Errorf("git: writing index: %w", err)
+	}
+	if err := l.lock.Close(); err != nil {
+		l.Unlock()
+		return fmt.Errorf("git: writing index: %w", err)
+	}
+	if err := os.Rename(l.lock.Name(), l.repo.indexPath()); err != nil {
+		l.Unlock()
+		return fmt.Errorf("git: writing index: %w", err)
+	}
+	return nil
+}
+
+// Unlock releases the lock without writing the index.
+func (l *LockedIndex) Unlock() {
+	_ = l.lock.Close()
+	_ = os.Remove(l.lock.Name())
+}
+
+func (idx *Index) encode(r *Repo) []byte {
+	var b []byte
+	b = append(b, "DIRC"...)
+	b = binary.BigEndian.AppendUint32(b, uint32(idx.Version))      //nolint:gosec
+	b = binary.BigEndian.AppendUint32(b, uint32(len(idx.Entries))) //nolint:gosec
+
+	prevPath := ""
+	for _, e := range idx.Entries {
+		start := len(b)
+		for _, v := range []uint32{
+			uint32(e.CTime.Unix()), uint32(e.CTime.Nanosecond()), //nolint:gosec
+			uint32(e.MTime.Unix()), uint32(e.MTime.Nanosecond()), //nolint:gosec
+			e.Dev, e.Ino, e.Mode, e.UID, e.GID, e.Size,
+		} {
+			b = binary.BigEndian.AppendUint32(b, v)
+		}
+		h, _ := hex.DecodeString(string(e.Hash))
+		b = append(b, h...)
+
+		flags := e.flags &^ (indexFlagStage | indexNameMask)
+		flags |= uint16(e.Stage<<12) & indexFlagStage //nolint:gosec
+		flags |= uint16(min(len(e.Path), indexNameMask))
+		b = binary.BigEndian.AppendUint16(b, flags)
+		if flags&indexFlagExtended != 0 {
+			b = binary.BigEndian.AppendUint16(b, e.extendedFlags)
+		}
+
+		if idx.Version == 4 {
+			common := 0
+			for common < len(prevPath) && common < len(e.Path) && prevPath[common] == e.Path[common] {
+				common++
+			}
+			b = appendOffsetVarint(b, len(prevPath)-common)
+			b = append(b, e.Path[common:]...)
+			b = append(b, 0)
+		} else {
+			b = append(b, e.Path...)
+			// At least one NUL, padding to a multiple of 8 bytes.
+			n := (len(b) - start + 8) &^ 7
+			b = append(b, make([]byte, start+n-len(b))...)
+		}
+		prevPath = e.Path
+	}
+
+	for _, ext := range idx.extensions {
+		b = append(b, ext.signature...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(ext.data))) //nolint:gosec
+		b = append(b, ext.data...)
+	}
+
+	h := r.newHash()
+	_, _ = h.Write(b)
+	return h.Sum(b)
+}
+
+// appendOffsetVarint appends v encoded as read by readOffsetVarint.
+func appendOffsetVarint(b []byte, v int) []byte {
+	var buf [16]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v != 0; v >>= 7 {
+		v--
+		i--
+		buf[i] = 0x80 | byte(v&0x7f)
+	}
+	return append(b, buf[i:]...)
+}
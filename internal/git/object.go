@@ -0,0 +1,548 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"container/list"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// https://git-scm.com/docs/gitformat-pack
+
+// ErrObjectNotFound is returned when an object is not in the repository.
+var ErrObjectNotFound = errors.New("git: object not found")
+
+// Object types.
+const (
+	TypeCommit = "commit"
+	TypeTree   = "tree"
+	TypeBlob   = "blob"
+	TypeTag    = "tag"
+)
+
+// Object types in packs.
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// maxDeltaDepth bounds delta chains to guard against corrupt packs.
+const maxDeltaDepth = 10000
+
+// deltaBaseCacheLimit is the total size of resolved delta bases cached per pack,
+// matching the default of git's core.deltaBaseCacheLimit.
+const deltaBaseCacheLimit = 96 << 20
+
+// objectStore reads objects from the loose object directories and packs of a
+// repository and its alternates.
+type objectStore struct {
+	hashSize int
+	dirs     []string
+	packs    []*pack
+
+	mu sync.Mutex
+}
+
+func (r *Repo) objectStore() (*objectStore, error) {
+	r.objectsOnce.Do(func() {
+		r.objects, r.objectsErr = openObjectStore(filepath.Join(r.commonDir, "objects"), r.hashSize())
+	})
+	return r.objects, r.objectsErr
+}
+
+func openObjectStore(dir string, hashSize int) (*objectStore, error) {
+	s := &objectStore{hashSize: hashSize}
+
+	dirs := []string{dir}
+	if b, err := os.ReadFile(filepath.Join(dir, "info", "alternates")); err == nil { //nolint:gosec
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || line[0] == '#' {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line)
+			}
+			dirs = append(dirs, line)
+		}
+	}
+	s.dirs = dirs
+
+	for _, d := range dirs {
+		idxs, _ := filepath.Glob(filepath.Join(d, "pack", "*.idx"))
+		for _, idx := range idxs {
+			p, err := openPack(strings.TrimSuffix(idx, ".idx"), hashSize)
+			if err != nil {
+				// Objects in other packs can still be read, and reading one only in
+				// this pack fails with ErrObjectNotFound.
+				slog.Debug(fmt.Sprintf("Skipping pack: %v", err))
+				continue
+			}
+			s.packs = append(s.packs, p)
+		}
+	}
+
+	return s, nil
+}
+
+// ReadObject returns the type and content of the object named h.
+func (r *Repo) ReadObject(h Hash) (string, []byte, error) {
+	s, err := r.objectStore()
+	if err != nil {
+		return "", nil, err
+	}
+	return s.read(h)
+}
+
+func (s *objectStore) read(h Hash) (string, []byte, error) {
+	for _, d := range s.dirs {
+		typ, b, err := readLooseObject(d, h)
+		if err == nil {
+			return typ, b, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", nil, err
+		}
+	}
+
+	raw, err := hex.DecodeString(string(h))
+	if err != nil || len(raw) != s.hashSize {
+		return "", nil, fmt.Errorf("git: invalid object name %q", h)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.packs {
+		if off, ok := p.find(raw); ok {
+			return p.read(s, off, 0)
+		}
+	}
+	return "", nil, fmt.Errorf("%w: %s", ErrObjectNotFound, h)
+}
+
+// expand returns the names of objects starting with prefix, for abbreviated
+// names.
+func (s *objectStore) expand(prefix string) []Hash {
+	seen := map[Hash]struct{}{}
+	if len(prefix) >= 2 {
+		for _, d := range s.dirs {
+			ents, _ := os.ReadDir(filepath.Join(d, prefix[:2]))
+			for _, e := range ents {
+				if strings.HasPrefix(e.Name(), prefix[2:]) {
+					seen[Hash(prefix[:2]+e.Name())] = struct{}{}
+				}
+			}
+		}
+	}
+	for _, p := range s.packs {
+		for _, h := range p.expand(prefix) {
+			seen[h] = struct{}{}
+		}
+	}
+	res := make([]Hash, 0, len(seen))
+	for h := range seen {
+		res = append(res, h)
+	}
+	return res
+}
+
+func readLooseObject(dir string, h Hash) (string, []byte, error) {
+	if len(h) < 3 {
+		return "", nil, os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(dir, string(h[:2]), string(h[2:]))) //nolint:gosec
+	if err != nil {
+		return "", nil, err //nolint:wrapcheck
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return "", nil, fmt.Errorf("git: reading object %s: %w", h, err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("git: reading object %s: %w", h, err)
+	}
+
+	hdr, content, ok := bytes.Cut(b, []byte{0})
+	if !ok {
+		return "", nil, fmt.Errorf("git: invalid object %s", h)
+	}
+	typ, size, _ := strings.Cut(string(hdr), " ")
+	if n, err := strconv.Atoi(size); err != nil || n != len(content) {
+		return "", nil, fmt.Errorf("git: invalid object %s", h)
+	}
+	return typ, content, nil
+}
+
+// WriteObject writes an object of type typ with content b as a loose object,
+// returning its name.
+func (r *Repo) WriteObject(typ string, b []byte) (Hash, error) {
+	h := r.HashObject(typ, b)
+
+	dir := filepath.Join(r.commonDir, "objects", string(h[:2]))
+	p := filepath.Join(dir, string(h[2:]))
+	if _, err := os.Stat(p); err == nil {
+		return h, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = fmt.Fprintf(zw, "%s %d\x00", typ, len(b))
+	_, _ = zw.Write(b)
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("git: compressing object: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("git: creating object directory: %w", err)
+	}
+	// Write to a temporary file and rename so readers never see a partial object.
+	f, err := os.CreateTemp(dir, "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("git: writing object: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("git: writing object: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("git: writing object: %w", err)
+	}
+	// Objects are read-only like git writes them.
+	_ = os.Chmod(f.Name(), 0o444)
+	if err := os.Rename(f.Name(), p); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("git: writing object: %w", err)
+	}
+	return h, nil
+}
+
+// pack is a packfile and its version 2 index.
+type pack struct {
+	path     string
+	hashSize int
+
+	fanout  [256]uint32
+	names   []byte
+	offsets []byte
+	large   []byte
+
+	f     *os.File
+	bases deltaBaseCache
+}
+
+func openPack(path string, hashSize int) (*pack, error) {
+	idx, err := os.ReadFile(path + ".idx") //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("git: reading pack index: %w", err)
+	}
+
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("git: unsupported pack index %s", path+".idx")
+	}
+
+	p := &pack{path: path, hashSize: hashSize}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	pos := 8 + 256*4
+	if len(idx) < pos+n*(hashSize+4+4) {
+		return nil, fmt.Errorf("git: truncated pack index %s", path+".idx")
+	}
+	p.names = idx[pos : pos+n*hashSize]
+	pos += n * hashSize
+	pos += n * 4 // CRCs
+	p.offsets = idx[pos : pos+n*4]
+	pos += n * 4
+	p.large = idx[pos:]
+
+	return p, nil
+}
+
+func (p *pack) name(i int) []byte {
+	return p.names[i*p.hashSize : (i+1)*p.hashSize]
+}
+
+func (p *pack) find(h []byte) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	hi := int(p.fanout[h[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.name(lo+i), h) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.name(i), h) {
+		return 0, false
+	}
+
+	off := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if off&0x80000000 == 0 {
+		return int64(off), true
+	}
+	li := int(off & 0x7fffffff)
+	return int64(binary.BigEndian.Uint64(p.large[li*8:])), true //nolint:gosec
+}
+
+func (p *pack) expand(prefix string) []Hash {
+	var res []Hash
+	for i := range int(p.fanout[255]) {
+		if h := hex.EncodeToString(p.name(i)); strings.HasPrefix(h, prefix) {
+			res = append(res, Hash(h))
+		}
+	}
+	return res
+}
+
+// read returns the object at off in the pack, resolving deltas. It must be
+// called with s.mu held.
+func (p *pack) read(s *objectStore, off int64, depth int) (string, []byte, error) {
+	if depth > maxDeltaDepth {
+		return "", nil, fmt.Errorf("git: delta chain too long in %s", p.path)
+	}
+
+	if p.f == nil {
+		f, err := os.Open(p.path + ".pack") //nolint:gosec
+		if err != nil {
+			return "", nil, fmt.Errorf("git: opening pack: %w", err)
+		}
+		p.f = f
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(p.f, off, 1<<62))
+	c, err := br.ReadByte()
+	if err != nil {
+		return "", nil, fmt.Errorf("git: reading pack: %w", err)
+	}
+	typ := (c >> 4) & 7
+	// The size in the header is not needed as zlib streams are self-terminating.
+	for c&0x80 != 0 {
+		if c, err = br.ReadByte(); err != nil {
+			return "", nil, fmt.Errorf("git: reading pack: %w", err)
+		}
+	}
+
+	var base struct {
+		typ string
+		b   []byte
+	}
+	switch typ {
+	case packOfsDelta:
+		c, err := br.ReadByte()
+		if err != nil {
+			return "", nil, fmt.Errorf("git: reading pack: %w", err)
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return "", nil, fmt.Errorf("git: reading pack: %w", err)
+			}
+			rel = ((rel + 1) << 7) | int64(c&0x7f)
+		}
+		base.typ, base.b, err = p.readBase(s, off-rel, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+	case packRefDelta:
+		h := make([]byte, p.hashSize)
+		if _, err := io.ReadFull(br, h); err != nil {
+			return "", nil, fmt.Errorf("git: reading pack: %w", err)
+		}
+		if boff, ok := p.find(h); ok {
+			base.typ, base.b, err = p.readBase(s, boff, depth+1)
+		} else {
+			// The base may be in any pack or loose.
+			s.mu.Unlock()
+			base.typ, base.b, err = s.read(Hash(hex.EncodeToString(h)))
+			s.mu.Lock()
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return "", nil, fmt.Errorf("git: reading pack: %w", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("git: reading pack: %w", err)
+	}
+
+	switch typ {
+	case packCommit:
+		return TypeCommit, b, nil
+	case packTree:
+		return TypeTree, b, nil
+	case packBlob:
+		return TypeBlob, b, nil
+	case packTag:
+		return TypeTag, b, nil
+	case packOfsDelta, packRefDelta:
+		res, err := applyDelta(base.b, b)
+		if err != nil {
+			return "", nil, fmt.Errorf("git: reading pack %s: %w", p.path, err)
+		}
+		return base.typ, res, nil
+	}
+	return "", nil, fmt.Errorf("git: unknown object type %d in %s", typ, p.path)
+}
+
+// readBase returns the delta base at off in the pack, from the cache if it was
+// resolved recently. Objects in the same chain share bases, so without it reading
+// them is quadratic in the chain length.
+func (p *pack) readBase(s *objectStore, off int64, depth int) (string, []byte, error) {
+	if typ, b, ok := p.bases.get(off); ok {
+		return typ, b, nil
+	}
+	typ, b, err := p.read(s, off, depth)
+	if err != nil {
+		return "", nil, err
+	}
+	p.bases.add(off, typ, b, deltaBaseCacheLimit)
+	return typ, b, nil
+}
+
+// deltaBaseCache is an LRU cache of resolved delta bases keyed by pack offset.
+// Cached contents are only read when applying deltas and must not be modified.
+type deltaBaseCache struct {
+	entries map[int64]*list.Element
+	lru     list.List
+	size    int
+}
+
+type deltaBase struct {
+	off int64
+	typ string
+	b   []byte
+}
+
+func (c *deltaBaseCache) get(off int64) (string, []byte, bool) {
+	e, ok := c.entries[off]
+	if !ok {
+		return "", nil, false
+	}
+	c.lru.MoveToFront(e)
+	base := e.Value.(*deltaBase) //nolint:forcetypeassert
+	return base.typ, base.b, true
+}
+
+// add caches a base, evicting the least recently used ones to stay within limit.
+func (c *deltaBaseCache) add(off int64, typ string, b []byte, limit int) {
+	if len(b) > limit {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[int64]*list.Element)
+	}
+	if _, ok := c.entries[off]; ok {
+		return
+	}
+	c.entries[off] = c.lru.PushFront(&deltaBase{off: off, typ: typ, b: b})
+	c.size += len(b)
+	for c.size > limit {
+		e := c.lru.Back()
+		base := c.lru.Remove(e).(*deltaBase) //nolint:forcetypeassert
+		delete(c.entries, base.off)
+		c.size -= len(base.b)
+	}
+}
+
+var errInvalidDelta = errors.New("invalid delta")
+
+// applyDelta applies a delta to base.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	readSize := func() (int, bool) {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+
+	srcSize, ok := readSize()
+	if !ok || srcSize != len(base) {
+		return nil, errInvalidDelta
+	}
+	dstSize, ok := readSize()
+	if !ok {
+		return nil, errInvalidDelta
+	}
+
+	res := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// Copy from base, with the offset and size in the bytes flagged by op.
+			var off, size int
+			for i := range 4 {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errInvalidDelta
+					}
+					off |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := range 3 {
+				if op&(1<<(4+i)) != 0 {
+					if len(delta) == 0 {
+						return nil, errInvalidDelta
+					}
+					size |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if off+size > len(base) {
+				return nil, errInvalidDelta
+			}
+			res = append(res, base[off:off+size]...)
+		case op != 0:
+			// Insert the next op bytes.
+			if int(op) > len(delta) {
+				return nil, errInvalidDelta
+			}
+			res = append(res, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errInvalidDelta
+		}
+	}
+	if len(res) != dstSize {
+		return nil, errInvalidDelta
+	}
+	return res, nil
+}
@@ -0,0 +1,214 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// https://git-scm.com/docs/gitrevisions
+
+// ErrUnknownRevision is returned by ResolveRevision when a revision does not name
+// an object.
+var ErrUnknownRevision = errors.New("git: unknown revision")
+
+// maxSymrefDepth bounds following symbolic refs to guard against cycles.
+const maxSymrefDepth = 5
+
+// ResolveRevision returns the commit named by rev, which may be a ref name, a full
+// or abbreviated object name, HEAD or @, followed by any number of ~N, ^ or ^N
+// suffixes. Annotated tags are peeled to the commit they point to.
+func (r *Repo) ResolveRevision(rev string) (Hash, error) {
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+	if base == "" || base == "@" {
+		base = "HEAD"
+	}
+
+	h, err := r.resolveBase(base)
+	if err != nil {
+		return "", err
+	}
+	if h, err = r.peelToCommit(h); err != nil {
+		return "", err
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		i := 0
+		for i < len(suffix) && suffix[i] >= '0' && suffix[i] <= '9' {
+			i++
+		}
+		n := 1
+		if i > 0 {
+			n, _ = strconv.Atoi(suffix[:i])
+		}
+		suffix = suffix[i:]
+
+		switch op {
+		case '~':
+			for range n {
+				if h, err = r.parent(h, 1, rev); err != nil {
+					return "", err
+				}
+			}
+		case '^':
+			if n == 0 {
+				continue
+			}
+			if h, err = r.parent(h, n, rev); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+		}
+	}
+
+	return h, nil
+}
+
+func (r *Repo) parent(h Hash, n int, rev string) (Hash, error) {
+	c, err := r.readCommit(h)
+	if err != nil {
+		return "", err
+	}
+	if n > len(c.parents) {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+	}
+	return c.parents[n-1], nil
+}
+
+// resolveBase resolves a revision without suffixes.
+func (r *Repo) resolveBase(name string) (Hash, error) {
+	// Ref lookup order from git rev-parse.
+	candidates := []string{
+		name,
+		"refs/" + name,
+		"refs/tags/" + name,
+		"refs/heads/" + name,
+		"refs/remotes/" + name,
+		"refs/remotes/" + name + "/HEAD",
+	}
+	for _, ref := range candidates {
+		h, err := r.resolveRef(ref, 0)
+		if err == nil {
+			return h, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	if isHex(name) && len(name) >= 4 {
+		s, err := r.objectStore()
+		if err != nil {
+			return "", err
+		}
+		if len(name) == r.hashSize()*2 {
+			return Hash(name), nil
+		}
+		matches := s.expand(strings.ToLower(name))
+		switch len(matches) {
+		case 1:
+			return matches[0], nil
+		case 0:
+		default:
+			return "", fmt.Errorf("git: ambiguous revision %s", name)
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownRevision, name)
+}
+
+// resolveRef returns the object the ref named name points to, following symbolic
+// refs. os.ErrNotExist is returned if there is no such ref.
+func (r *Repo) resolveRef(name string, depth int) (Hash, error) {
+	if depth > maxSymrefDepth {
+		return "", fmt.Errorf("git: too many levels of symbolic refs for %s", name)
+	}
+
+	// HEAD and other pseudorefs belong to the worktree, other refs are shared.
+	dir := r.commonDir
+	if !strings.HasPrefix(name, "refs/") {
+		dir = r.gitDir
+	}
+	b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))) //nolint:gosec
+	if err == nil {
+		v := strings.TrimSpace(string(b))
+		if target, ok := strings.CutPrefix(v, "ref: "); ok {
+			return r.resolveRef(target, depth+1)
+		}
+		if isHex(v) && len(v) == r.hashSize()*2 {
+			return Hash(v), nil
+		}
+		// A directory or other file that isn't a ref.
+		return "", os.ErrNotExist
+	}
+
+	if strings.HasPrefix(name, "refs/") {
+		if h, ok := r.packedRef(name); ok {
+			return h, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func (r *Repo) packedRef(name string) (Hash, bool) {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		h, ref, ok := strings.Cut(line, " ")
+		if ok && ref == name {
+			return Hash(h), true
+		}
+	}
+	return "", false
+}
+
+// peelToCommit follows annotated tags until reaching a commit.
+func (r *Repo) peelToCommit(h Hash) (Hash, error) {
+	for range maxSymrefDepth {
+		typ, b, err := r.ReadObject(h)
+		if err != nil {
+			return "", err
+		}
+		switch typ {
+		case TypeCommit:
+			return h, nil
+		case TypeTag:
+			t, ok := header(b, "object")
+			if !ok {
+				return "", fmt.Errorf("git: invalid tag %s", h)
+			}
+			h = Hash(t)
+		default:
+			return "", fmt.Errorf("git: %s is a %s, not a commit", h, typ)
+		}
+	}
+	return "", fmt.Errorf("git: too many levels of tags for %s", h)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
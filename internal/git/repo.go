@@ -0,0 +1,285 @@
+// Package git reads and writes the subset of a local git repository needed to
+// find changed files: refs, objects, trees and the index. It does not support
+// network operations and does not execute git.
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // git uses SHA-1 for object names
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotRepository is returned by Find when no repository contains a directory.
+var ErrNotRepository = errors.New("git: not a git repository")
+
+// Hash is the name of an object as lowercase hex.
+type Hash string
+
+// Repo is a git repository with a working tree.
+type Repo struct {
+	// Root is the root directory of the working tree.
+	Root string
+
+	// gitDir is the git directory of the working tree, which contains HEAD and the
+	// index. It differs from commonDir for worktrees added with git worktree.
+	gitDir string
+	// commonDir contains objects and refs.
+	commonDir string
+
+	sha256 bool
+
+	objectsOnce sync.Once
+	objects     *objectStore
+	objectsErr  error
+}
+
+// Find returns the repository containing dir by searching up for a .git directory
+// or file.
+func Find(dir string) (*Repo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("git: resolving directory: %w", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return Open(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotRepository
+		}
+		dir = parent
+	}
+}
+
+// Open opens the repository with its working tree at root.
+func Open(root string) (*Repo, error) {
+	gitDir := filepath.Join(root, ".git")
+	fi, err := os.Stat(gitDir)
+	if err != nil {
+		return nil, fmt.Errorf("git: opening repository: %w", err)
+	}
+	if !fi.IsDir() {
+		// Worktrees and submodules have a file pointing to the git directory.
+		b, err := os.ReadFile(gitDir) //nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("git: reading .git file: %w", err)
+		}
+		p, ok := strings.CutPrefix(strings.TrimSpace(string(b)), "gitdir: ")
+		if !ok {
+			return nil, fmt.Errorf("git: invalid .git file in %s", root)
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		gitDir = p
+	}
+
+	commonDir := gitDir
+	if b, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil { //nolint:gosec
+		p := strings.TrimSpace(string(b))
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(gitDir, p)
+		}
+		commonDir = p
+	}
+
+	r := &Repo{
+		Root:      root,
+		gitDir:    gitDir,
+		commonDir: commonDir,
+	}
+	r.sha256 = strings.EqualFold(r.configValue("extensions", "objectformat"), "sha256")
+	return r, nil
+}
+
+// hashSize is the size in bytes of object names.
+func (r *Repo) hashSize() int {
+	if r.sha256 {
+		return sha256.Size
+	}
+	return sha1.Size
+}
+
+func (r *Repo) newHash() hash.Hash {
+	if r.sha256 {
+		return sha256.New()
+	}
+	return sha1.New() //nolint:gosec
+}
+
+// HashObject returns the name of an object of type typ with content b, such as
+// for a blob of a file in the working tree.
+func (r *Repo) HashObject(typ string, b []byte) Hash {
+	h := r.newHash()
+	_, _ = fmt.Fprintf(h, "%s %d\x00", typ, len(b))
+	_, _ = h.Write(b)
+	return Hash(hex.EncodeToString(h.Sum(nil)))
+}
+
+// MatchesBlob reports whether b, the contents of a file in the working tree, is
+// stored as the blob h. Like git add, CRLF line endings in text files are converted
+// to LF when core.autocrlf is true or input in the repository or global config.
+// Conversions configured through the text and eol attributes in .gitattributes
+// are not applied, so such files may be reported as not matching.
+func (r *Repo) MatchesBlob(b []byte, h Hash) bool {
+	if r.HashObject(TypeBlob, b) == h {
+		// Files with CRLF in the blob are not converted.
+		return true
+	}
+	if !r.autoCRLF() || !bytes.Contains(b, []byte("\r\n")) || !isText(b) {
+		return false
+	}
+	return r.HashObject(TypeBlob, bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))) == h
+}
+
+func (r *Repo) autoCRLF() bool {
+	v := r.configValue("core", "autocrlf")
+	if v == "" {
+		v = globalConfigValue("core", "autocrlf")
+	}
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1", "input":
+		return true
+	}
+	return false
+}
+
+// isText reports whether git converts line endings in b with core.autocrlf, which
+// it doesn't for content with NUL bytes or a CR not followed by LF.
+func isText(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return false
+	}
+	for i, c := range b {
+		if c == '\r' && (i+1 == len(b) || b[i+1] != '\n') {
+			return false
+		}
+	}
+	return true
+}
+
+// ExcludesFiles returns the paths of the files with ignore patterns that apply to
+// the whole repository, in increasing order of priority: the global excludes file
+// (core.excludesFile) and .git/info/exclude. Files may not exist.
+func (r *Repo) ExcludesFiles() []string {
+	var res []string
+	if p := globalExcludesFile(r.configValue("core", "excludesfile")); p != "" {
+		res = append(res, p)
+	}
+	return append(res, filepath.Join(r.commonDir, "info", "exclude"))
+}
+
+// globalExcludesFile returns the path of the global excludes file, which is
+// core.excludesFile from the repository or global config, defaulting to
+// $XDG_CONFIG_HOME/git/ignore.
+func globalExcludesFile(local string) string {
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+
+	p := local
+	if p == "" {
+		p = globalConfigValue("core", "excludesfile")
+	}
+
+	switch {
+	case p == "":
+		if xdg == "" {
+			return ""
+		}
+		return filepath.Join(xdg, "git", "ignore")
+	case p == "~" || strings.HasPrefix(p, "~/"):
+		if home == "" {
+			return ""
+		}
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+// globalConfigValue returns the value of key in section from the global config,
+// or an empty string if it is not set.
+func globalConfigValue(section, key string) string {
+	var files []string
+	if g := os.Getenv("GIT_CONFIG_GLOBAL"); g != "" {
+		files = append(files, g)
+	} else {
+		home, _ := os.UserHomeDir()
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		if xdg == "" && home != "" {
+			xdg = filepath.Join(home, ".config")
+		}
+		if xdg != "" {
+			files = append(files, filepath.Join(xdg, "git", "config"))
+		}
+		if home != "" {
+			files = append(files, filepath.Join(home, ".gitconfig"))
+		}
+	}
+
+	// Like git, later files take precedence.
+	res := ""
+	for _, f := range files {
+		if v := readConfigValue(f, section, key); v != "" {
+			res = v
+		}
+	}
+	return res
+}
+
+// configValue returns the value of key in section from the repository config, or
+// an empty string if it is not set. Only simple sections are supported, which is
+// enough for the few settings we read.
+func (r *Repo) configValue(section, key string) string {
+	return readConfigValue(filepath.Join(r.commonDir, "config"), section, key)
+}
+
+func readConfigValue(path string, section, key string) string {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	res := ""
+	inSection := false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			name, _, _ := strings.Cut(strings.Trim(line, "[]"), " ")
+			inSection = strings.EqualFold(name, section)
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		k, v, _ := strings.Cut(line, "=")
+		if !strings.EqualFold(strings.TrimSpace(k), key) {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if uq, err := strconv.Unquote(v); err == nil {
+			v = uq
+		}
+		// Later values override earlier ones.
+		res = v
+	}
+	return res
+}
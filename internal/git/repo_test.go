@@ -0,0 +1,340 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/git/gittest"
+)
+
+func TestRepo(t *testing.T) {
+	tests := []struct {
+		name     string
+		initArgs []string
+		pack     bool
+	}{
+		{name: "loose"},
+		{name: "packed", pack: true},
+		{name: "sha256", initArgs: []string{"--object-format=sha256"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, run := gittest.NewRepo(t, tc.initArgs...)
+
+			gittest.WriteFile(t, dir, "a.md", "# a\n")
+			gittest.WriteFile(t, dir, "sub/b.md", "# b\n")
+			run("add", ".")
+			run("commit", "-q", "-m", "first")
+			first := run("rev-parse", "HEAD")
+			run("tag", "-a", "-m", "v1", "v1")
+
+			// Similar content so packs store it as a delta.
+			gittest.WriteFile(t, dir, "a.md", "# a\n\nmore\n")
+			gittest.WriteFile(t, dir, "c.md", "# c\n")
+			run("add", ".")
+			run("commit", "-q", "-m", "second")
+			second := run("rev-parse", "HEAD")
+
+			if tc.pack {
+				run("gc", "-q", "--aggressive")
+				_, err := os.Stat(filepath.Join(dir, ".git", "packed-refs"))
+				require.NoError(t, err)
+				// A pack with an index version we can't read is skipped.
+				gittest.WriteFile(t, dir, ".git/objects/pack/pack-old.idx", "\xfftOc\x00\x00\x00\x03")
+			}
+
+			r, err := Find(filepath.Join(dir, "sub"))
+			require.NoError(t, err)
+			require.Equal(t, dir, r.Root)
+
+			for rev, want := range map[string]string{
+				"HEAD":         second,
+				"@":            second,
+				"main":         second,
+				"heads/main":   second,
+				"HEAD~":        first,
+				"HEAD^":        first,
+				"main~1":       first,
+				"@^1":          first,
+				"HEAD^0":       second,
+				"v1":           first,
+				"tags/v1":      first,
+				second:         second,
+				first[:7]:      first,
+				"HEAD~1^0":     first,
+				"refs/tags/v1": first,
+			} {
+				h, err := r.ResolveRevision(rev)
+				require.NoError(t, err, rev)
+				require.Equal(t, Hash(want), h, rev)
+			}
+
+			_, err = r.ResolveRevision("HEAD~2")
+			require.ErrorIs(t, err, ErrUnknownRevision)
+			_, err = r.ResolveRevision("missing")
+			require.ErrorIs(t, err, ErrUnknownRevision)
+
+			files, err := r.CommitFiles(Hash(second))
+			require.NoError(t, err)
+			require.Len(t, files, 3)
+			for name, content := range map[string]string{
+				"a.md":     "# a\n\nmore\n",
+				"sub/b.md": "# b\n",
+				"c.md":     "# c\n",
+			} {
+				require.Equal(t, r.HashObject(TypeBlob, []byte(content)), files[name].Hash, name)
+				require.Equal(t, run("rev-parse", "HEAD:"+name), string(files[name].Hash), name)
+
+				typ, b, err := r.ReadObject(files[name].Hash)
+				require.NoError(t, err)
+				require.Equal(t, TypeBlob, typ)
+				require.Equal(t, content, string(b))
+			}
+
+			files, err = r.CommitFiles(Hash(first))
+			require.NoError(t, err)
+			require.Len(t, files, 2)
+			require.Equal(t, run("rev-parse", first+":a.md"), string(files["a.md"].Hash))
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for _, version := range []string{"2", "3", "4"} {
+		t.Run("v"+version, func(t *testing.T) {
+			dir, run := gittest.NewRepo(t)
+
+			gittest.WriteFile(t, dir, "a.md", "# a\n")
+			gittest.WriteFile(t, dir, "dir/b.md", "# b\n")
+			gittest.WriteFile(t, dir, "dir/c.md", "# c\n")
+			gittest.WriteFile(t, dir, "intent.md", "# intent\n")
+			run("add", "a.md", "dir")
+			if version == "2" {
+				run("add", "intent.md")
+			} else {
+				// Intent-to-add sets an extended flag, which requires version 3.
+				run("add", "-N", "intent.md")
+			}
+			run("update-index", "--index-version", version)
+
+			r, err := Open(dir)
+			require.NoError(t, err)
+			idx, err := r.ReadIndex()
+			require.NoError(t, err)
+			require.Equal(t, version, strconv.Itoa(idx.Version))
+
+			var paths []string
+			for _, e := range idx.Entries {
+				paths = append(paths, e.Path)
+			}
+			require.Equal(t, []string{"a.md", "dir/b.md", "dir/c.md", "intent.md"}, paths)
+
+			e := idx.Entry("dir/c.md")
+			require.NotNil(t, e)
+			require.Equal(t, r.HashObject(TypeBlob, []byte("# c\n")), e.Hash)
+			require.Equal(t, uint32(4), e.Size)
+			require.Nil(t, idx.Entry("missing.md"))
+		})
+	}
+}
+
+func TestFindNotRepository(t *testing.T) {
+	_, err := Find(t.TempDir())
+	require.ErrorIs(t, err, ErrNotRepository)
+}
+
+func TestWorktree(t *testing.T) {
+	dir, run := gittest.NewRepo(t)
+	gittest.WriteFile(t, dir, "a.md", "# a\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	head := run("rev-parse", "HEAD")
+
+	wt := filepath.Join(t.TempDir(), "wt")
+	run("worktree", "add", "-q", "-b", "other", wt)
+
+	r, err := Find(wt)
+	require.NoError(t, err)
+	h, err := r.ResolveRevision("HEAD")
+	require.NoError(t, err)
+	require.Equal(t, Hash(head), h)
+	idx, err := r.ReadIndex()
+	require.NoError(t, err)
+	require.NotNil(t, idx.Entry("a.md"))
+}
+
+func TestWriteIndex(t *testing.T) {
+	for _, version := range []string{"2", "3", "4"} {
+		t.Run("v"+version, func(t *testing.T) {
+			dir, run := gittest.NewRepo(t)
+
+			gittest.WriteFile(t, dir, "a.md", "# a\n")
+			gittest.WriteFile(t, dir, "dir/b.md", "# b\n")
+			gittest.WriteFile(t, dir, "dir/c.md", "# c\n")
+			run("add", ".")
+			run("commit", "-q", "-m", "first")
+			if version != "2" {
+				gittest.WriteFile(t, dir, "intent.md", "# intent\n")
+				run("add", "-N", "intent.md")
+			}
+			run("update-index", "--index-version", version)
+			// Populate the untracked cache, which must be dropped when writing.
+			run("update-index", "--untracked-cache")
+			run("status", "--untracked-files=all")
+
+			r, err := Open(dir)
+			require.NoError(t, err)
+
+			idx, err := r.LockIndex()
+			require.NoError(t, err)
+			_, err = r.LockIndex()
+			require.ErrorIs(t, err, ErrIndexLocked)
+
+			h, err := r.WriteObject(TypeBlob, []byte("# b\n\nstaged\n"))
+			require.NoError(t, err)
+			require.Equal(t, run("hash-object", "dir/b.md"), string(r.HashObject(TypeBlob, []byte("# b\n"))))
+			e := idx.Entry("dir/b.md")
+			e.Hash = h
+			e.Invalidate()
+			require.NoError(t, idx.Commit())
+
+			b, err := os.ReadFile(filepath.Join(dir, ".git", "index"))
+			require.NoError(t, err)
+			require.NotContains(t, string(b), "UNTR")
+
+			require.Equal(t, "# b\n\nstaged", run("show", ":dir/b.md"))
+			require.Equal(t, "dir/b.md", run("diff", "--cached", "--name-only"))
+			// The working tree still has the old content.
+			require.Equal(t, "dir/b.md", run("diff", "--name-only", "--", "dir"))
+			run("fsck", "--no-progress")
+
+			idx, err = r.LockIndex()
+			require.NoError(t, err)
+			require.Equal(t, version, strconv.Itoa(idx.Version))
+			idx.Unlock()
+		})
+	}
+}
+
+func TestExcludesFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+
+	dir, run := gittest.NewRepo(t)
+	r, err := Open(dir)
+	require.NoError(t, err)
+	exclude := filepath.Join(dir, ".git", "info", "exclude")
+
+	// Defaults to the XDG location.
+	require.Equal(t, []string{filepath.Join(home, ".config", "git", "ignore"), exclude}, r.ExcludesFiles())
+
+	gittest.WriteFile(t, home, ".gitconfig", "[core]\n\texcludesFile = ~/global-ignore\n")
+	require.Equal(t, []string{filepath.Join(home, "global-ignore"), exclude}, r.ExcludesFiles())
+
+	// The repository config takes precedence.
+	run("config", "core.excludesFile", "/repo-ignore")
+	require.Equal(t, []string{"/repo-ignore", exclude}, r.ExcludesFiles())
+}
+
+func TestMatchesBlob(t *testing.T) {
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+
+	dir, run := gittest.NewRepo(t)
+	run("config", "core.autocrlf", "true")
+	gittest.WriteFile(t, dir, "lf.md", "# a\r\n\nb\r\n")
+	gittest.WriteFile(t, dir, "binary.md", "# a\r\n\x00\r\n")
+	gittest.WriteFile(t, dir, "lone-cr.md", "# a\r\n\rb\r\n")
+	run("add", ".")
+	run("config", "core.autocrlf", "false")
+	gittest.WriteFile(t, dir, "crlf.md", "# a\r\n")
+	gittest.WriteFile(t, dir, "attributes.md", "# a\r\n")
+	gittest.WriteFile(t, dir, ".gitattributes", "attributes.md text eol=crlf\n")
+	// Only add the new files as the others would be hashed again if racily clean.
+	run("add", "crlf.md", "attributes.md", ".gitattributes")
+	run("commit", "-q", "-m", "first")
+
+	r, err := Open(dir)
+	require.NoError(t, err)
+	matches := func(name string) bool {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return r.MatchesBlob(b, Hash(run("rev-parse", "HEAD:"+name)))
+	}
+
+	// Without core.autocrlf, files stored with LF don't match.
+	require.False(t, matches("lf.md"))
+	require.True(t, matches("crlf.md"))
+	require.True(t, matches("binary.md"))
+	require.True(t, matches("lone-cr.md"))
+	// Attributes are not applied.
+	require.False(t, matches("attributes.md"))
+
+	for _, v := range []string{"true", "input"} {
+		run("config", "core.autocrlf", v)
+		require.True(t, matches("lf.md"))
+		require.True(t, matches("crlf.md"))
+		require.True(t, matches("binary.md"))
+		require.True(t, matches("lone-cr.md"))
+	}
+
+	// The global config applies when the repository doesn't set it.
+	run("config", "--unset", "core.autocrlf")
+	require.False(t, matches("lf.md"))
+	gittest.WriteFile(t, filepath.Dir(global), filepath.Base(global), "[core]\n\tautocrlf = true\n")
+	require.True(t, matches("lf.md"))
+}
+
+func TestLockIndexSparse(t *testing.T) {
+	dir, run := gittest.NewRepo(t)
+
+	gittest.WriteFile(t, dir, "a.md", "# a\n")
+	gittest.WriteFile(t, dir, "dir/b.md", "# b\n")
+	gittest.WriteFile(t, dir, "other/c.md", "# c\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	run("sparse-checkout", "set", "--cone", "--sparse-index", "dir")
+
+	r, err := Open(dir)
+	require.NoError(t, err)
+	_, err = r.LockIndex()
+	require.ErrorContains(t, err, "sparse index is not supported")
+
+	// The index is not left locked.
+	_, err = os.Stat(filepath.Join(dir, ".git", "index.lock"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDeltaBaseCache(t *testing.T) {
+	var c deltaBaseCache
+	c.add(1, TypeBlob, []byte("aaaa"), 10)
+	c.add(2, TypeBlob, []byte("bbbb"), 10)
+	// Too large to cache at all.
+	c.add(3, TypeBlob, []byte("ccccccccccc"), 10)
+	_, _, ok := c.get(3)
+	require.False(t, ok)
+
+	// Using 1 makes 2 the least recently used, so it's evicted first.
+	typ, b, ok := c.get(1)
+	require.True(t, ok)
+	require.Equal(t, TypeBlob, typ)
+	require.Equal(t, "aaaa", string(b))
+	c.add(4, TypeTree, []byte("dddd"), 10)
+
+	_, _, ok = c.get(2)
+	require.False(t, ok)
+	_, _, ok = c.get(1)
+	require.True(t, ok)
+	_, _, ok = c.get(4)
+	require.True(t, ok)
+	require.Equal(t, 8, c.size)
+}
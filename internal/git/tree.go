@@ -0,0 +1,121 @@
+package git
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// File modes of tree entries.
+const (
+	ModeDir     = 0o040000
+	ModeSymlink = 0o120000
+	ModeGitlink = 0o160000
+)
+
+type commit struct {
+	tree    Hash
+	parents []Hash
+}
+
+func (r *Repo) readCommit(h Hash) (commit, error) {
+	typ, b, err := r.ReadObject(h)
+	if err != nil {
+		return commit{}, err
+	}
+	if typ != TypeCommit {
+		return commit{}, fmt.Errorf("git: %s is a %s, not a commit", h, typ)
+	}
+
+	var c commit
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if len(line) == 0 {
+			// End of headers.
+			break
+		}
+		k, v, _ := bytes.Cut(line, []byte(" "))
+		switch string(k) {
+		case "tree":
+			c.tree = Hash(v)
+		case "parent":
+			c.parents = append(c.parents, Hash(v))
+		}
+	}
+	if c.tree == "" {
+		return commit{}, fmt.Errorf("git: invalid commit %s", h)
+	}
+	return c, nil
+}
+
+// header returns the value of the header key of a commit or tag object.
+func header(b []byte, key string) (string, bool) {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if len(line) == 0 {
+			break
+		}
+		if k, v, ok := bytes.Cut(line, []byte(" ")); ok && string(k) == key {
+			return string(v), true
+		}
+	}
+	return "", false
+}
+
+// TreeEntry is a file in a tree.
+type TreeEntry struct {
+	Mode uint32
+	Hash Hash
+}
+
+// CommitFiles returns the files in the tree of the commit h, keyed by their
+// slash-separated path relative to the repository root. Submodules are not
+// included.
+func (r *Repo) CommitFiles(h Hash) (map[string]TreeEntry, error) {
+	c, err := r.readCommit(h)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]TreeEntry{}
+	if err := r.readTree(c.tree, "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *Repo) readTree(h Hash, prefix string, files map[string]TreeEntry) error {
+	typ, b, err := r.ReadObject(h)
+	if err != nil {
+		return err
+	}
+	if typ != TypeTree {
+		return fmt.Errorf("git: %s is a %s, not a tree", h, typ)
+	}
+
+	size := r.hashSize()
+	for len(b) > 0 {
+		sp := bytes.IndexByte(b, ' ')
+		nul := bytes.IndexByte(b, 0)
+		if sp < 0 || nul < sp || len(b) < nul+1+size {
+			return fmt.Errorf("git: invalid tree %s", h)
+		}
+		mode, err := strconv.ParseUint(string(b[:sp]), 8, 32)
+		if err != nil {
+			return fmt.Errorf("git: invalid tree %s", h)
+		}
+		name := path.Join(prefix, string(b[sp+1:nul]))
+		eh := Hash(hex.EncodeToString(b[nul+1 : nul+1+size]))
+		b = b[nul+1+size:]
+
+		switch uint32(mode) {
+		case ModeDir:
+			if err := r.readTree(eh, name, files); err != nil {
+				return err
+			}
+		case ModeGitlink:
+		default:
+			files[name] = TreeEntry{Mode: uint32(mode), Hash: eh}
+		}
+	}
+	return nil
+}
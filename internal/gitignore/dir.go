@@ -5,14 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 )
 
 const (
-	commentPrefix   = "#"
-	gitDir          = ".git"
-	gitignoreFile   = ".gitignore"
-	infoExcludeFile = gitDir + "/info/exclude"
+	commentPrefix = "#"
 )
 
 // ReadIgnoreFile reads a specific git ignore file.
@@ -37,34 +36,114 @@ func ReadIgnoreFile(path string, ignoreFile string) (ps []Pattern, err error) {
 	return
 }
 
-// ReadPatterns reads the .git/info/exclude and then the gitignore patterns
-// recursively traversing through the directory structure. The result is in
-// the ascending order of priority (last higher).
-func ReadPatterns(path string) (ps []Pattern, err error) {
-	ps, _ = ReadIgnoreFile(path, infoExcludeFile)
+// NewRepoMatcher returns a Matcher for the ignore files named ignoreFile, such as
+// .gitignore, in root and its subdirectories, as well as the patterns in
+// excludesFiles, which apply to the whole tree in increasing order of priority,
+// such as .git/info/exclude. Patterns in each ignore file are relative to its
+// directory. Paths given to Match must be absolute.
+//
+// Ignore files are read lazily as directories are matched and not at all within
+// ignored directories, like git, so matching while walking the tree does not
+// read ignore files of directories that are skipped.
+func NewRepoMatcher(root string, ignoreFile string, excludesFiles []string) Matcher {
+	rootParts := strings.Split(root, string(filepath.Separator))
+	var ps []Pattern
+	for _, f := range excludesFiles {
+		// Patterns in excludes files are relative to the working tree root.
+		b, err := os.ReadFile(f) //nolint:gosec
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if !strings.HasPrefix(line, commentPrefix) && len(strings.TrimSpace(line)) > 0 {
+				ps = append(ps, ParsePattern(line, rootParts))
+			}
+		}
+	}
+	return &repoMatcher{
+		rootParts:  rootParts,
+		ignoreFile: ignoreFile,
+		excludes:   ps,
+		dirs:       map[string]*dirPatterns{},
+	}
+}
 
-	subps, _ := ReadIgnoreFile(path, gitignoreFile)
-	ps = append(ps, subps...)
+type repoMatcher struct {
+	rootParts  []string
+	ignoreFile string
+	excludes   []Pattern
 
-	var fis []os.DirEntry
-	fis, err = os.ReadDir(path)
-	if err != nil {
-		return
+	mu   sync.Mutex
+	dirs map[string]*dirPatterns
+}
+
+// dirPatterns are the patterns that apply within a directory, from ignore files
+// in it and its parents.
+type dirPatterns struct {
+	patterns []Pattern
+	// ignored is set if the directory itself is ignored, in which case everything
+	// in it is too.
+	ignored bool
+}
+
+func (m *repoMatcher) Match(path []string, isDir bool) bool {
+	if len(path) <= len(m.rootParts) {
+		return false
 	}
+	for i, p := range m.rootParts {
+		if path[i] != p {
+			return false
+		}
+	}
+	m.mu.Lock()
+	d := m.dir(path[:len(path)-1])
+	m.mu.Unlock()
 
-	for _, fi := range fis {
-		if fi.IsDir() && fi.Name() != gitDir {
-			var subps []Pattern
-			subps, err = ReadPatterns(filepath.Join(path, fi.Name()))
-			if err != nil {
-				return
-			}
+	if d.ignored {
+		return true
+	}
+	return matchPatterns(d.patterns, path, isDir)
+}
 
-			if len(subps) > 0 {
-				ps = append(ps, subps...)
-			}
+// dir returns the patterns for the directory with path dirParts, reading its
+// ignore file if it is not ignored. It must be called with m.mu held.
+func (m *repoMatcher) dir(dirParts []string) *dirPatterns {
+	key := strings.Join(dirParts, string(filepath.Separator))
+	if d, ok := m.dirs[key]; ok {
+		return d
+	}
+
+	var d *dirPatterns
+	if len(dirParts) == len(m.rootParts) {
+		d = &dirPatterns{patterns: m.excludes}
+	} else {
+		parent := m.dir(dirParts[:len(dirParts)-1])
+		switch {
+		case parent.ignored:
+			d = parent
+		case matchPatterns(parent.patterns, dirParts, true):
+			d = &dirPatterns{ignored: true}
+		default:
+			d = &dirPatterns{patterns: slices.Clip(parent.patterns)}
+		}
+	}
+	if !d.ignored {
+		ps, _ := ReadIgnoreFile(key, m.ignoreFile)
+		if len(ps) > 0 {
+			d = &dirPatterns{patterns: append(d.patterns, ps...)}
 		}
 	}
 
-	return
+	m.dirs[key] = d
+	return d
+}
+
+func matchPatterns(ps []Pattern, path []string, isDir bool) bool {
+	for i := len(ps) - 1; i >= 0; i-- {
+		if match := ps[i].Match(path, isDir); match > NoMatch {
+			return match == Exclude
+		}
+	}
+	return false
 }
@@ -0,0 +1,94 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1" //nolint:revive
+)
+
+type RepoMatcherSuite struct {
+	root string
+}
+
+var _ = Suite(&RepoMatcherSuite{})
+
+func (s *RepoMatcherSuite) SetUpTest(c *C) {
+	s.root = c.MkDir()
+
+	for name, content := range map[string]string{
+		".gitignore":          "*.log\n!keep.log\nbuild/\n",
+		"sub/.gitignore":      "*.tmp\n/local.txt\n",
+		"sub/deep/a.txt":      "",
+		"build/.gitignore":    "!*\n",
+		"global":              "secret.txt\n",
+		".git/info/exclude":   "private/\n",
+		"private/.gitignore":  "!*\n",
+		"sub/deep/.gitignore": "!b.tmp\n",
+	} {
+		p := filepath.Join(s.root, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0o755), IsNil)
+		c.Assert(os.WriteFile(p, []byte(content), 0o644), IsNil)
+	}
+}
+
+func (s *RepoMatcherSuite) match(m Matcher, path string, isDir bool) bool {
+	p := filepath.Join(s.root, filepath.FromSlash(path))
+	return m.Match(strings.Split(p, string(filepath.Separator)), isDir)
+}
+
+func (s *RepoMatcherSuite) TestMatch(c *C) {
+	m := NewRepoMatcher(s.root, ".gitignore", []string{
+		filepath.Join(s.root, "global"),
+		filepath.Join(s.root, ".git", "info", "exclude"),
+		filepath.Join(s.root, "missing"),
+	})
+
+	for path, ignored := range map[string]bool{
+		"a.log":              true,
+		"keep.log":           false,
+		"a.txt":              false,
+		"sub/a.log":          true,
+		"sub/a.tmp":          true,
+		"a.tmp":              false,
+		"sub/local.txt":      true,
+		"sub/deep/local.txt": false,
+		"sub/deep/a.tmp":     true,
+		"sub/deep/b.tmp":     false,
+		"secret.txt":         true,
+		"sub/secret.txt":     true,
+		// Negations in ignored directories have no effect.
+		"build/a.txt":   true,
+		"private/a.txt": true,
+	} {
+		c.Assert(s.match(m, path, false), Equals, ignored, Commentf("path: %s", path))
+	}
+
+	c.Assert(s.match(m, "build", true), Equals, true)
+	c.Assert(s.match(m, "build", false), Equals, false)
+	c.Assert(s.match(m, "private", true), Equals, true)
+
+	// Paths outside the repository are never ignored.
+	c.Assert(m.Match(strings.Split(filepath.Join(filepath.Dir(s.root), "a.log"), string(filepath.Separator)), false), Equals, false)
+}
+
+func (s *RepoMatcherSuite) TestLazy(c *C) {
+	m := NewRepoMatcher(s.root, ".gitignore", nil)
+
+	c.Assert(s.match(m, "build/a.txt", false), Equals, true)
+	c.Assert(s.match(m, "sub/a.tmp", false), Equals, true)
+
+	// Only ignore files of directories that were matched in and are not ignored
+	// are read.
+	var dirs []string
+	for dir, d := range m.(*repoMatcher).dirs {
+		if !d.ignored {
+			rel, err := filepath.Rel(s.root, dir)
+			c.Assert(err, IsNil)
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+	}
+	c.Assert(dirs, HasLen, 2)
+	c.Assert(strings.Join(dirs, ","), Matches, `(\.,sub|sub,\.)`)
+}
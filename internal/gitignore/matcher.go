@@ -20,11 +20,5 @@ type matcher struct {
 }
 
 func (m *matcher) Match(path []string, isDir bool) bool {
-	n := len(m.patterns)
-	for i := n - 1; i >= 0; i-- {
-		if match := m.patterns[i].Match(path, isDir); match > NoMatch {
-			return match == Exclude
-		}
-	}
-	return false
+	return matchPatterns(m.patterns, path, isDir)
 }
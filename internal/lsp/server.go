@@ -0,0 +1,355 @@
+// Package lsp implements a language server that formats documents with prettier.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/wasilibs/go-prettier/v3/internal/runner"
+)
+
+// Formatter formats the content of a file. It is implemented by *runner.Runner.
+type Formatter interface {
+	FormatFile(ctx context.Context, in []byte, filePath string, args runner.RunArgs) ([]byte, error)
+}
+
+// Server is a language server that formats documents. Requests are handled one at
+// a time, in order, which keeps document content consistent with formatting.
+type Server struct {
+	f    Formatter
+	args runner.RunArgs
+
+	// docs maps the URI of each open document to its content.
+	docs map[string]string
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a Server that formats with f. args configures how config and
+// ignore files are resolved like for the CLI. Its Cwd is replaced with the
+// workspace root when the client provides one.
+func NewServer(f Formatter, args runner.RunArgs) *Server {
+	return &Server{
+		f:    f,
+		args: args,
+		docs: map[string]string{},
+	}
+}
+
+// Serve handles messages from r, writing responses to w, until the client sends
+// exit or r is closed. It returns an error if the client exits without first
+// requesting shutdown, in which case the process should exit with a non-zero code.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		b, err := readMessage(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) && s.shutdown {
+				return nil
+			}
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(b, &msg); err != nil {
+			if err := writeMessage(w, response{JSONRPC: "2.0", Error: &responseError{Code: codeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("lsp: exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(ctx, msg)
+
+		// Notifications have no ID and don't get a response.
+		if msg.ID == nil {
+			if err != nil {
+				slog.DebugContext(ctx, fmt.Sprintf("Error handling %s: %v", msg.Method, err))
+			}
+			continue
+		}
+
+		res := response{JSONRPC: "2.0", ID: msg.ID, Result: result}
+		if err != nil {
+			var re *responseError
+			if !errors.As(err, &re) {
+				re = &responseError{Code: codeRequestFailed, Message: err.Error()}
+			}
+			res.Result = nil
+			res.Error = re
+		}
+		if err := writeMessage(w, res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, msg message) (any, error) {
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		// We only advertise full sync but apply ranges anyway for robustness.
+		doc := s.docs[params.TextDocument.URI]
+		for _, c := range params.ContentChanges {
+			if c.Range == nil {
+				doc = c.Text
+				continue
+			}
+			start := byteOffset(doc, c.Range.Start)
+			end := byteOffset(doc, c.Range.End)
+			doc = doc[:start] + c.Text + doc[end:]
+		}
+		s.docs[params.TextDocument.URI] = doc
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/formatting":
+		var params documentFormattingParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.format(ctx, params.TextDocument.URI, nil)
+	case "textDocument/rangeFormatting":
+		var params documentRangeFormattingParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.format(ctx, params.TextDocument.URI, &params.Range)
+	case "textDocument/onTypeFormatting":
+		var params documentOnTypeFormattingParams
+		if err := unmarshalParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		// Format the statements on the line up to the typed character, so that
+		// code still being written after it is left alone.
+		return s.format(ctx, params.TextDocument.URI, &lspRange{
+			Start: position{Line: params.Position.Line},
+			End:   params.Position,
+		})
+	}
+
+	if strings.HasPrefix(msg.Method, "$/") {
+		// Optional protocol messages such as $/cancelRequest can be ignored.
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+func (s *Server) initialize(params initializeParams) initializeResult {
+	s.initialized = true
+
+	root := ""
+	switch {
+	case len(params.WorkspaceFolders) > 0:
+		root = uriToPath(params.WorkspaceFolders[0].URI)
+	case params.RootURI != "":
+		root = uriToPath(params.RootURI)
+	case params.RootPath != "":
+		root = params.RootPath
+	}
+	if root != "" {
+		s.args.Cwd = root
+	}
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:                textDocumentSyncFull,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentOnTypeFormattingProvider: documentOnTypeFormattingOpts{
+				FirstTriggerCharacter: "}",
+				MoreTriggerCharacter:  []string{";"},
+			},
+		},
+		ServerInfo: serverInfo{Name: "prettier"},
+	}
+}
+
+// format formats the document at uri, restricted to rng if it is not nil, and
+// returns the edits to apply. No edits are returned for files that are ignored
+// or have no parser, like editor extensions for prettier.
+func (s *Server) format(ctx context.Context, uri string, rng *lspRange) ([]textEdit, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document not open: %s", uri)}
+	}
+
+	args := s.args
+	if rng != nil {
+		args.Options = maps.Clone(args.Options)
+		if args.Options == nil {
+			args.Options = map[string]any{}
+		}
+		args.Options["rangeStart"] = runner.UTF16Offset([]byte(doc), byteOffset(doc, rng.Start))
+		args.Options["rangeEnd"] = runner.UTF16Offset([]byte(doc), byteOffset(doc, rng.End))
+	}
+
+	res, err := s.f.FormatFile(ctx, []byte(doc), uriToPath(uri), args)
+	if errors.Is(err, runner.ErrIgnored) || errors.Is(err, runner.ErrNoParser) {
+		return []textEdit{}, nil
+	}
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return diffEdits(doc, string(res)), nil
+}
+
+// diffEdits returns the edits to change a into b. A single edit is returned
+// covering the changed region between the common prefix and suffix, which keeps
+// the cursor and folding in place in editors better than replacing the whole
+// document.
+func diffEdits(a, b string) []textEdit {
+	if a == b {
+		return []textEdit{}
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	// Don't split UTF-8 sequences.
+	for prefix > 0 && !utf8.RuneStart(a[prefix]) {
+		prefix--
+	}
+	for suffix > 0 && !utf8.RuneStart(a[len(a)-suffix]) {
+		suffix--
+	}
+
+	return []textEdit{{
+		Range: lspRange{
+			Start: positionAt(a, prefix),
+			End:   positionAt(a, len(a)-suffix),
+		},
+		NewText: b[prefix : len(b)-suffix],
+	}}
+}
+
+// byteOffset converts pos to a byte offset in doc, clamping positions past the
+// end of a line or the document.
+func byteOffset(doc string, pos position) int {
+	off := 0
+	for range pos.Line {
+		i := strings.IndexByte(doc[off:], '\n')
+		if i < 0 {
+			return len(doc)
+		}
+		off += i + 1
+	}
+
+	line := doc[off:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	n := 0
+	for i, r := range line {
+		if n >= pos.Character {
+			return off + i
+		}
+		n += utf16RuneLen(r)
+	}
+	return off + len(line)
+}
+
+// positionAt converts the byte offset off in doc to a position.
+func positionAt(doc string, off int) position {
+	before := doc[:off]
+	line := strings.Count(before, "\n")
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	char := 0
+	for _, r := range before {
+		char += utf16RuneLen(r)
+	}
+	return position{Line: line, Character: char}
+}
+
+func utf16RuneLen(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// uriToPath returns the file path for a file URI. Other URIs, such as for unsaved
+// documents, are returned as their path, which is still useful for inferring the
+// parser.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	p := u.Path
+	if u.Scheme == "file" && runtime.GOOS == "windows" {
+		// file:///C:/foo has a path of /C:/foo
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p)
+}
+
+func unmarshalParams(b json.RawMessage, v any) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/runner"
+)
+
+// fakeFormatter uppercases content, recording the arguments it was called with.
+type fakeFormatter struct {
+	filePath string
+	args     runner.RunArgs
+}
+
+func (f *fakeFormatter) FormatFile(_ context.Context, in []byte, filePath string, args runner.RunArgs) ([]byte, error) {
+	f.filePath = filePath
+	f.args = args
+	switch {
+	case strings.HasSuffix(filePath, ".ignored"):
+		return nil, runner.ErrIgnored
+	case strings.HasSuffix(filePath, ".invalid"):
+		return nil, &runner.SyntaxError{Path: filePath, Message: "Unexpected token"}
+	}
+	return bytes.ToUpper(in), nil
+}
+
+func TestServer(t *testing.T) {
+	var in bytes.Buffer
+	send := func(id int, method string, params any) {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+		if id != 0 {
+			msg["id"] = id
+		}
+		require.NoError(t, writeMessage(&in, msg))
+	}
+
+	send(1, "initialize", map[string]any{"rootUri": "file:///work"})
+	send(0, "initialized", map[string]any{})
+	send(0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///work/a.md", "languageId": "markdown", "version": 1, "text": "x\nhello\ny\n"},
+	})
+	send(2, "textDocument/formatting", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///work/a.md"},
+		"options":      map[string]any{"tabSize": 2, "insertSpaces": true},
+	})
+	send(0, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": "file:///work/a.md", "version": 2},
+		"contentChanges": []any{map[string]any{"text": "é\nhello\n"}},
+	})
+	send(3, "textDocument/rangeFormatting", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///work/a.md"},
+		"range":        map[string]any{"start": map[string]any{"line": 1, "character": 0}, "end": map[string]any{"line": 1, "character": 5}},
+	})
+	send(0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///work/a.ignored", "text": "a"},
+	})
+	send(4, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///work/a.ignored"}})
+	send(0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": "file:///work/a.invalid", "text": "a"},
+	})
+	send(5, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///work/a.invalid"}})
+	send(6, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": "file:///work/closed.md"}})
+	send(7, "unknown/method", nil)
+	send(8, "shutdown", nil)
+	send(0, "exit", nil)
+
+	f := &fakeFormatter{}
+	var out bytes.Buffer
+	require.NoError(t, NewServer(f, runner.RunArgs{Cwd: "."}).Serve(t.Context(), &in, &out))
+
+	responses := map[int]json.RawMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		b, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var res struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *responseError  `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(b, &res))
+		if res.Error != nil {
+			responses[res.ID] = json.RawMessage(fmt.Sprintf(`{"code":%d}`, res.Error.Code))
+		} else {
+			responses[res.ID] = res.Result
+		}
+	}
+	require.Len(t, responses, 8)
+
+	var initRes initializeResult
+	require.NoError(t, json.Unmarshal(responses[1], &initRes))
+	require.True(t, initRes.Capabilities.DocumentFormattingProvider)
+	require.True(t, initRes.Capabilities.DocumentRangeFormattingProvider)
+
+	// Only the changed region between the common prefix and suffix is edited.
+	require.JSONEq(t, `[{"range":{"start":{"line":0,"character":0},"end":{"line":2,"character":1}},"newText":"X\nHELLO\nY"}]`, string(responses[2]))
+
+	// Range offsets are passed to prettier in UTF-16 code units.
+	require.JSONEq(t, `[{"range":{"start":{"line":0,"character":0},"end":{"line":1,"character":5}},"newText":"É\nHELLO"}]`, string(responses[3]))
+
+	require.JSONEq(t, `[]`, string(responses[4]))
+	require.JSONEq(t, fmt.Sprintf(`{"code":%d}`, codeRequestFailed), string(responses[5]))
+	require.JSONEq(t, fmt.Sprintf(`{"code":%d}`, codeInvalidParams), string(responses[6]))
+	require.JSONEq(t, fmt.Sprintf(`{"code":%d}`, codeMethodNotFound), string(responses[7]))
+	require.JSONEq(t, `null`, string(responses[8]))
+}
+
+func TestServerRangeOptions(t *testing.T) {
+	var in bytes.Buffer
+	require.NoError(t, writeMessage(&in, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}}))
+	require.NoError(t, writeMessage(&in, map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": "file:///a.md", "text": "é\nhello\n"},
+	}}))
+	require.NoError(t, writeMessage(&in, map[string]any{"jsonrpc": "2.0", "id": 2, "method": "textDocument/rangeFormatting", "params": map[string]any{
+		"textDocument": map[string]any{"uri": "file:///a.md"},
+		"range":        map[string]any{"start": map[string]any{"line": 1, "character": 1}, "end": map[string]any{"line": 1, "character": 3}},
+	}}))
+
+	f := &fakeFormatter{}
+	var out bytes.Buffer
+	// The reader ends without shutdown, which is an error.
+	require.Error(t, NewServer(f, runner.RunArgs{Cwd: "."}).Serve(t.Context(), &in, &out))
+
+	require.Equal(t, 3, f.args.Options["rangeStart"])
+	require.Equal(t, 5, f.args.Options["rangeEnd"])
+}
+
+func TestPositions(t *testing.T) {
+	doc := "ab\n😀c\n"
+
+	tests := []struct {
+		pos position
+		off int
+	}{
+		{pos: position{Line: 0, Character: 0}, off: 0},
+		{pos: position{Line: 0, Character: 2}, off: 2},
+		{pos: position{Line: 1, Character: 0}, off: 3},
+		{pos: position{Line: 1, Character: 2}, off: 7},
+		{pos: position{Line: 1, Character: 3}, off: 8},
+		{pos: position{Line: 2, Character: 0}, off: 9},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.off, byteOffset(doc, tc.pos), "%+v", tc.pos)
+		require.Equal(t, tc.pos, positionAt(doc, tc.off), "%d", tc.off)
+	}
+
+	// Positions past the end of a line or document are clamped.
+	require.Equal(t, 2, byteOffset(doc, position{Line: 0, Character: 10}))
+	require.Equal(t, 9, byteOffset(doc, position{Line: 5, Character: 0}))
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wasilibs/go-prettier/v3/internal/wasm"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/cli/format-results-cache.js
+
+// Values for RunArgs.CacheStrategy.
+const (
+	CacheStrategyMetadata = "metadata"
+	CacheStrategyContent  = "content"
+)
+
+// bundleHash identifies the embedded prettier bundle, so that results cached by a
+// different version of prettier or its plugins are not reused.
+var bundleHash = sync.OnceValue(func() string {
+	h := sha256.Sum256(wasm.Prettier)
+	return hex.EncodeToString(h[:])
+})
+
+type cacheEntry struct {
+	Size    int64  `json:"size,omitempty"`
+	ModTime int64  `json:"mtime,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Options string `json:"options"`
+}
+
+type cacheFile struct {
+	Version string                `json:"version"`
+	Files   map[string]cacheEntry `json:"files"`
+}
+
+// formatCache records files that are known to already be formatted with a given
+// set of options.
+type formatCache struct {
+	path     string
+	strategy string
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// cacheFilePath returns the path of the cache file for args, with a relative
+// --cache-location resolved against Cwd.
+func cacheFilePath(args RunArgs) string {
+	switch {
+	case args.CacheLocation == "":
+		return findCacheFile(args.Cwd)
+	case filepath.IsAbs(args.CacheLocation):
+		return args.CacheLocation
+	}
+	return filepath.Join(args.Cwd, args.CacheLocation)
+}
+
+// findCacheFile returns the cache location to use when none is specified. Like upstream,
+// node_modules/.cache/prettier is used next to the closest package.json, otherwise the
+// user cache directory.
+func findCacheFile(cwd string) string {
+	if p := findConfigFile(cwd, "package.json"); p != "" {
+		return filepath.Join(filepath.Dir(p), "node_modules", ".cache", "prettier", ".prettier-cache")
+	}
+	if uc, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(uc, "com.github.wasilibs", "prettier", ".prettier-cache")
+	}
+	return filepath.Join(os.TempDir(), ".prettier-cache")
+}
+
+func loadFormatCache(ctx context.Context, path string, strategy string) *formatCache {
+	c := &formatCache{
+		path:     path,
+		strategy: strategy,
+		entries:  map[string]cacheEntry{},
+	}
+
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return c
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		slog.DebugContext(ctx, fmt.Sprintf(`Ignoring invalid cache file "%s": %v`, path, err))
+		return c
+	}
+	if f.Version != bundleHash() {
+		return c
+	}
+	if f.Files != nil {
+		c.entries = f.Files
+	}
+
+	return c
+}
+
+// isFormatted returns whether the file at path with content and options cfg was
+// previously recorded as formatted.
+func (c *formatCache) isFormatted(path string, fi os.FileInfo, content []byte, cfg map[string]any) bool {
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	cur, ok := c.newEntry(fi, content, cfg)
+	return ok && e == cur
+}
+
+func (c *formatCache) setFormatted(path string, fi os.FileInfo, content []byte, cfg map[string]any) {
+	e, ok := c.newEntry(fi, content, cfg)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = e
+	c.dirty = true
+}
+
+// newEntry returns the cache entry for a file, or false if cfg can't be encoded,
+// such as with a non-finite number from a YAML or TOML config, in which case
+// the file is not cached.
+func (c *formatCache) newEntry(fi os.FileInfo, content []byte, cfg map[string]any) (cacheEntry, bool) {
+	var e cacheEntry
+	if c.strategy == CacheStrategyMetadata {
+		e.Size = fi.Size()
+		e.ModTime = fi.ModTime().UnixNano()
+	} else {
+		h := sha256.Sum256(content)
+		e.Hash = hex.EncodeToString(h[:])
+	}
+
+	// Maps are marshaled with sorted keys so the encoding is stable.
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	h := sha256.Sum256(cfgBytes)
+	e.Options = hex.EncodeToString(h[:])
+
+	return e, true
+}
+
+func (c *formatCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	b, err := json.Marshal(cacheFile{
+		Version: bundleHash(),
+		Files:   c.entries,
+	})
+	if err != nil {
+		// Programming bug
+		panic(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("runner: creating cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, b, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("runner: writing cache file: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
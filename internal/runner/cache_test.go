@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatCache(t *testing.T) {
+	for _, strategy := range []string{CacheStrategyContent, CacheStrategyMetadata} {
+		t.Run(strategy, func(t *testing.T) {
+			dir := t.TempDir()
+			cachePath := filepath.Join(dir, "cache", ".prettier-cache")
+
+			file := filepath.Join(dir, "a.md")
+			content := []byte("# Hello\n")
+			require.NoError(t, os.WriteFile(file, content, 0o644))
+			fi, err := os.Stat(file)
+			require.NoError(t, err)
+
+			cfg := map[string]any{"tabWidth": 4, "filepath": file}
+
+			c := loadFormatCache(t.Context(), cachePath, strategy)
+			require.False(t, c.isFormatted(file, fi, content, cfg))
+			c.setFormatted(file, fi, content, cfg)
+			require.NoError(t, c.save())
+
+			c = loadFormatCache(t.Context(), cachePath, strategy)
+			require.True(t, c.isFormatted(file, fi, content, cfg))
+			require.False(t, c.isFormatted(file, fi, content, map[string]any{"tabWidth": 2, "filepath": file}))
+
+			changed := []byte("# Hello, world\n")
+			require.NoError(t, os.WriteFile(file, changed, 0o644))
+			fi, err = os.Stat(file)
+			require.NoError(t, err)
+			require.False(t, c.isFormatted(file, fi, changed, cfg))
+		})
+	}
+}
+
+func TestFormatCacheUnencodableConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.md")
+	content := []byte("# Hello\n")
+	require.NoError(t, os.WriteFile(file, content, 0o644))
+	fi, err := os.Stat(file)
+	require.NoError(t, err)
+
+	// YAML and TOML configs can contain infinity, which can't be encoded as JSON.
+	cfg := map[string]any{"printWidth": math.Inf(1), "filepath": file}
+
+	c := loadFormatCache(t.Context(), filepath.Join(dir, ".prettier-cache"), CacheStrategyContent)
+	c.setFormatted(file, fi, content, cfg)
+	require.False(t, c.isFormatted(file, fi, content, cfg))
+}
+
+func TestCacheFilePath(t *testing.T) {
+	cwd := t.TempDir()
+	abs := filepath.Join(t.TempDir(), "cache", ".prettier-cache")
+
+	require.Equal(t, abs, cacheFilePath(RunArgs{Cwd: cwd, CacheLocation: abs}))
+	require.Equal(t, filepath.Join(cwd, "cache", "c"), cacheFilePath(RunArgs{Cwd: cwd, CacheLocation: filepath.Join("cache", "c")}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(cwd, "package.json"), []byte("{}"), 0o644))
+	require.Equal(t, filepath.Join(cwd, "node_modules", ".cache", "prettier", ".prettier-cache"), cacheFilePath(RunArgs{Cwd: cwd}))
+}
+
+func TestRequestedCursorOffset(t *testing.T) {
+	// A cache hit reports the requested offset, which is a float64 from config files.
+	require.Equal(t, -1, requestedCursorOffset(map[string]any{}))
+	require.Equal(t, 3, requestedCursorOffset(map[string]any{"cursorOffset": 3}))
+	require.Equal(t, 3, requestedCursorOffset(map[string]any{"cursorOffset": 3.0}))
+}
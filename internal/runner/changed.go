@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/wasilibs/go-prettier/v3/internal/git"
+)
+
+// defaultPatterns returns the patterns to format for args. Like pretty-quick,
+// --staged and --changed-since format all changed files when run without
+// patterns, as from a git hook.
+func defaultPatterns(args RunArgs) []string {
+	if len(args.Patterns) == 0 && (args.Staged || args.ChangedSince != "") {
+		return []string{"."}
+	}
+	return args.Patterns
+}
+
+// filterChanged restricts paths to files that differ from args.ChangedSince or,
+// with args.Staged, have staged changes, like pretty-quick. Paths with errors are
+// kept so they are still reported.
+func filterChanged(ctx context.Context, args RunArgs, paths []expandedPath) ([]expandedPath, error) {
+	if args.ChangedSince == "" && !args.Staged {
+		return paths, nil
+	}
+
+	repo, err := git.Find(args.Cwd)
+	if err != nil {
+		return nil, fmt.Errorf("runner: finding git repository: %w", err)
+	}
+
+	var changed func(rel, path string) (bool, error)
+	if args.Staged {
+		changed, err = stagedFilter(repo)
+	} else {
+		changed, err = changedSinceFilter(repo, args.ChangedSince)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res []expandedPath
+	for _, p := range paths {
+		if p.error != "" {
+			res = append(res, p)
+			continue
+		}
+		abs, err := filepath.Abs(p.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("runner: resolving path: %w", err)
+		}
+		rel, err := filepath.Rel(repo.Root, abs)
+		if err != nil {
+			return nil, fmt.Errorf("runner: resolving path: %w", err)
+		}
+		ok, err := changed(filepath.ToSlash(rel), abs)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, p)
+		} else {
+			slog.DebugContext(ctx, fmt.Sprintf("Skipping unchanged file %s", p.filePath))
+		}
+	}
+	return res, nil
+}
+
+// changedSinceFilter returns a function reporting whether the working tree file
+// at path differs from rev. Files not in rev, including untracked files, are
+// changed.
+func changedSinceFilter(repo *git.Repo, rev string) (func(rel, path string) (bool, error), error) {
+	h, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return nil, fmt.Errorf("runner: resolving --changed-since: %w", err)
+	}
+	files, err := repo.CommitFiles(h)
+	if err != nil {
+		return nil, fmt.Errorf("runner: reading --changed-since: %w", err)
+	}
+	idx, err := repo.ReadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("runner: reading git index: %w", err)
+	}
+
+	return func(rel, path string) (bool, error) {
+		f, ok := files[rel]
+		if !ok {
+			return true, nil
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return false, fmt.Errorf("runner: reading file: %w", err)
+		}
+		// Avoid hashing files the index already knows are the same as rev.
+		if e := idx.Entry(rel); e != nil && e.Hash == f.Hash && idx.Unchanged(e, fi) {
+			return false, nil
+		}
+		b, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return false, fmt.Errorf("runner: reading file: %w", err)
+		}
+		return !repo.MatchesBlob(b, f.Hash), nil
+	}, nil
+}
+
+// stagedFilter returns a function reporting whether the file has changes staged
+// in the index relative to HEAD.
+func stagedFilter(repo *git.Repo) (func(rel, path string) (bool, error), error) {
+	idx, err := repo.ReadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("runner: reading git index: %w", err)
+	}
+
+	var head map[string]git.TreeEntry
+	switch h, err := repo.ResolveRevision("HEAD"); {
+	case errors.Is(err, git.ErrUnknownRevision):
+		// Everything is staged before the first commit.
+	case err != nil:
+		return nil, fmt.Errorf("runner: resolving HEAD: %w", err)
+	default:
+		if head, err = repo.CommitFiles(h); err != nil {
+			return nil, fmt.Errorf("runner: reading HEAD: %w", err)
+		}
+	}
+
+	return func(rel, _ string) (bool, error) {
+		e := idx.Entry(rel)
+		if e == nil {
+			return false, nil
+		}
+		f, ok := head[rel]
+		return !ok || f.Hash != e.Hash, nil
+	}, nil
+}
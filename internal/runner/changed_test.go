@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/git/gittest"
+)
+
+func TestFilterChanged(t *testing.T) {
+	dir, git := gittest.NewRepo(t)
+
+	paths := func(names ...string) []expandedPath {
+		var res []expandedPath
+		for _, n := range names {
+			res = append(res, expandedPath{filePath: filepath.Join(dir, n)})
+		}
+		return res
+	}
+	all := paths("committed.md", "modified.md", "staged.md", "sub/untracked.md")
+	all = append(all, expandedPath{error: "No files matching the pattern were found"})
+
+	gittest.WriteFile(t, dir, "committed.md", "# committed\n")
+	gittest.WriteFile(t, dir, "modified.md", "# modified\n")
+	gittest.WriteFile(t, dir, "staged.md", "# staged\n")
+
+	// Before the first commit, everything in the index is staged.
+	git("add", ".")
+	res, err := filterChanged(context.Background(), RunArgs{Cwd: dir, Staged: true}, all)
+	require.NoError(t, err)
+	require.Equal(t, append(paths("committed.md", "modified.md", "staged.md"), all[4]), res)
+
+	git("commit", "-q", "-m", "first")
+	git("tag", "first")
+
+	gittest.WriteFile(t, dir, "modified.md", "# modified\n\nagain\n")
+	gittest.WriteFile(t, dir, "staged.md", "# staged\n\nagain\n")
+	gittest.WriteFile(t, dir, "sub/untracked.md", "# untracked\n")
+	git("add", "staged.md")
+
+	tests := []struct {
+		name     string
+		args     RunArgs
+		expected []expandedPath
+	}{
+		{
+			name:     "no filter",
+			args:     RunArgs{Cwd: dir},
+			expected: all,
+		},
+		{
+			name:     "changed since HEAD",
+			args:     RunArgs{Cwd: dir, ChangedSince: "HEAD"},
+			expected: append(paths("modified.md", "staged.md", "sub/untracked.md"), all[4]),
+		},
+		{
+			name:     "changed since tag from subdirectory",
+			args:     RunArgs{Cwd: filepath.Join(dir, "sub"), ChangedSince: "first"},
+			expected: append(paths("modified.md", "staged.md", "sub/untracked.md"), all[4]),
+		},
+		{
+			name:     "staged",
+			args:     RunArgs{Cwd: dir, Staged: true},
+			expected: append(paths("staged.md"), all[4]),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := filterChanged(context.Background(), tc.args, all)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res)
+		})
+	}
+
+	_, err = filterChanged(context.Background(), RunArgs{Cwd: dir, ChangedSince: "missing"}, all)
+	require.ErrorContains(t, err, "unknown revision")
+
+	_, err = filterChanged(context.Background(), RunArgs{Cwd: t.TempDir(), ChangedSince: "HEAD"}, all)
+	require.ErrorContains(t, err, "not a git repository")
+}
+
+func TestDefaultPatterns(t *testing.T) {
+	require.Equal(t, []string{"."}, defaultPatterns(RunArgs{Staged: true}))
+	require.Equal(t, []string{"."}, defaultPatterns(RunArgs{ChangedSince: "main"}))
+	require.Equal(t, []string{"src"}, defaultPatterns(RunArgs{Staged: true, Patterns: []string{"src"}}))
+	require.Empty(t, defaultPatterns(RunArgs{}))
+}
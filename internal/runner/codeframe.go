@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// https://github.com/babel/babel/blob/main/packages/babel-code-frame/src/index.ts
+
+const (
+	codeFrameLinesAbove = 2
+	codeFrameLinesBelow = 3
+)
+
+// codeFrame renders the lines of src around loc with a marker under the start of
+// the location, in the same format as prettier.
+func codeFrame(src string, loc *Location) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	if loc.Start.Line < 1 || loc.Start.Line > len(lines) {
+		return ""
+	}
+
+	first := max(loc.Start.Line-codeFrameLinesAbove, 1)
+	last := min(loc.Start.Line+codeFrameLinesBelow, len(lines))
+	numberWidth := len(strconv.Itoa(last))
+
+	var sb strings.Builder
+	for n := first; n <= last; n++ {
+		line := lines[n-1]
+		number := strconv.Itoa(n)
+		gutter := " " + strings.Repeat(" ", numberWidth-len(number)) + number + " |"
+
+		if n > first {
+			sb.WriteByte('\n')
+		}
+		if n != loc.Start.Line {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteByte('>')
+		}
+		sb.WriteString(gutter)
+		if line != "" {
+			sb.WriteByte(' ')
+			sb.WriteString(line)
+		}
+
+		if n != loc.Start.Line {
+			continue
+		}
+
+		col := min(max(loc.Start.Column, 1), len(line)+1)
+		markers := 1
+		if loc.End != nil && loc.End.Line == loc.Start.Line && loc.End.Column > loc.Start.Column {
+			markers = loc.End.Column - loc.Start.Column
+		}
+
+		sb.WriteString("\n ")
+		sb.WriteString(strings.Repeat(" ", len(gutter)-1))
+		sb.WriteString("| ")
+		// Keep tabs so the marker lines up with the source.
+		for _, c := range line[:col-1] {
+			if c == '\t' {
+				sb.WriteByte('\t')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(strings.Repeat("^", markers))
+	}
+
+	return sb.String()
+}
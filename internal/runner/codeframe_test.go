@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeFrame(t *testing.T) {
+	src := "a: 1\nb: 2\nc: [1, 2\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\n"
+
+	tests := []struct {
+		name string
+		loc  Location
+		exp  string
+	}{
+		{
+			name: "middle",
+			loc:  Location{Start: Position{Line: 3, Column: 4}},
+			exp: "  1 | a: 1\n" +
+				"  2 | b: 2\n" +
+				"> 3 | c: [1, 2\n" +
+				"    |    ^\n" +
+				"  4 | d: 4\n" +
+				"  5 | e: 5\n" +
+				"  6 | f: 6",
+		},
+		{
+			name: "range",
+			loc:  Location{Start: Position{Line: 1, Column: 1}, End: &Position{Line: 1, Column: 2}},
+			exp: "> 1 | a: 1\n" +
+				"    | ^\n" +
+				"  2 | b: 2\n" +
+				"  3 | c: [1, 2\n" +
+				"  4 | d: 4",
+		},
+		{
+			name: "wide range",
+			loc:  Location{Start: Position{Line: 3, Column: 4}, End: &Position{Line: 3, Column: 9}},
+			exp: "  1 | a: 1\n" +
+				"  2 | b: 2\n" +
+				"> 3 | c: [1, 2\n" +
+				"    |    ^^^^^\n" +
+				"  4 | d: 4\n" +
+				"  5 | e: 5\n" +
+				"  6 | f: 6",
+		},
+		{
+			name: "last line",
+			loc:  Location{Start: Position{Line: 9, Column: 1}},
+			exp: "  7 | g: 7\n" +
+				"  8 | h: 8\n" +
+				"> 9 |\n" +
+				"    | ^",
+		},
+		{
+			name: "out of range",
+			loc:  Location{Start: Position{Line: 20, Column: 1}},
+			exp:  "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, codeFrame(src, &tc.loc))
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/config/prettier-config/config-searcher.js
+
+// configFileNames are the config files searched for in each directory, in order of
+// precedence. TS configs are not supported.
+var configFileNames = []string{
+	"package.json",
+	"package.yaml",
+	".prettierrc",
+	".prettierrc.json",
+	".prettierrc.yaml",
+	".prettierrc.yml",
+	".prettierrc.json5",
+	".prettierrc.jsonc",
+	".prettierrc.js",
+	".prettierrc.mjs",
+	".prettierrc.cjs",
+	"prettier.config.js",
+	"prettier.config.mjs",
+	"prettier.config.cjs",
+	".prettierrc.toml",
+}
+
+func findConfigFile(cwd string, name string) string {
+	dir, err := filepath.Abs(cwd)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || parent == "" {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+func isPackageFile(path string) bool {
+	switch filepath.Base(path) {
+	case "package.json", "package.yaml":
+		return true
+	}
+	return false
+}
+
+func hasPrettierKey(path string) bool {
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return false
+	}
+	v, err := parseConfigFile(path, b)
+	if err != nil {
+		return false
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, ok = m["prettier"]
+	return ok
+}
+
+// loadConfigFile loads the config file at path. JS configs are evaluated with
+// evalJS and are not supported if it is nil.
+func loadConfigFile(ctx context.Context, path string, evalJS jsEvaluator) (map[string]any, error) {
+	return loadConfigFileShared(ctx, path, evalJS, true)
+}
+
+// loadConfigFileShared loads the config file at path. If allowShared is set, the
+// config may be a string referencing a shared config, which is loaded instead.
+// Shared configs can't reference other shared configs, like upstream.
+func loadConfigFileShared(ctx context.Context, path string, evalJS jsEvaluator, allowShared bool) (map[string]any, error) {
+	res := map[string]any{}
+
+	var v any
+	if isJSConfig(path) {
+		if evalJS == nil {
+			slog.WarnContext(ctx, fmt.Sprintf(`Unable to load config file "%s"`, path))
+			slog.WarnContext(ctx, "JS configs are not supported.")
+			return res, errInvalidConfigFile
+		}
+		var err error
+		v, err = evalJS(ctx, path)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf(`Invalid config file "%s"`, path))
+			slog.WarnContext(ctx, err.Error())
+			return res, errInvalidConfigFile
+		}
+	} else {
+		pCfgBytes, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf(`Unable to read config file "%s"`, path))
+			slog.WarnContext(ctx, err.Error())
+			return res, fmt.Errorf("runner: reading config file: %w", err)
+		}
+
+		v, err = parseConfigFile(path, pCfgBytes)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf(`Invalid config file "%s"`, path))
+			slog.WarnContext(ctx, err.Error())
+			return res, errInvalidConfigFile
+		}
+	}
+
+	if isPackageFile(path) {
+		if m, ok := v.(map[string]any); ok {
+			v = m["prettier"]
+		}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return res, nil
+	case map[string]any:
+		return v, nil
+	case string:
+		if allowShared {
+			// A string references a shared config.
+			return loadSharedConfig(ctx, filepath.Dir(path), v, evalJS)
+		}
+	}
+
+	slog.WarnContext(ctx, fmt.Sprintf(`Invalid config file "%s"`, path))
+	slog.WarnContext(ctx, "Config must be an object or a string referencing a shared config.")
+	return res, errInvalidConfigFile
+}
+
+func parseConfigFile(path string, b []byte) (any, error) {
+	switch filepath.Ext(path) {
+	case ".json5", ".jsonc":
+		return parseJSON5(b)
+	case ".toml":
+		var res map[string]any
+		if err := toml.Unmarshal(b, &res); err != nil {
+			return nil, fmt.Errorf("runner: parsing TOML: %w", err)
+		}
+		return res, nil
+	}
+
+	if filepath.Base(path) == "package.json" {
+		var res any
+		if err := json.Unmarshal(b, &res); err != nil {
+			return nil, fmt.Errorf("runner: parsing JSON: %w", err)
+		}
+		return res, nil
+	}
+
+	// YAML is superset of JSON so it should be fine to only use YAML to parse.
+	var res map[string]any
+	err := yaml.Unmarshal(b, &res)
+	if err == nil {
+		return res, nil
+	}
+
+	if err := toml.Unmarshal(b, &res); err == nil {
+		return res, nil
+	}
+
+	// Only check for a string after other formats since YAML would also parse
+	// TOML as a string.
+	var ref string
+	if err := yaml.Unmarshal(b, &ref); err == nil {
+		return ref, nil
+	}
+
+	// JSON / YAML are more common so use it's error rather than TOML's
+	return nil, fmt.Errorf("runner: parsing config: %w", err)
+}
+
+// sharedConfigExtensions are tried in order when a shared config reference has
+// no extension, like Node's module resolution but for the formats we can load.
+var sharedConfigExtensions = []string{".json", ".yaml", ".yml", ".toml", ".json5", ".js", ".cjs", ".mjs"}
+
+// loadSharedConfig loads the config referenced by ref, a relative path or the
+// name of a package in node_modules, relative to dir.
+// https://github.com/prettier/prettier/blob/main/src/config/prettier-config/load-external-config.js
+func loadSharedConfig(ctx context.Context, dir string, ref string, evalJS jsEvaluator) (map[string]any, error) {
+	path, err := resolveSharedConfig(dir, ref)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf(`Cannot find module "%s" from "%s"`, ref, dir))
+		slog.WarnContext(ctx, err.Error())
+		return map[string]any{}, errInvalidConfigFile
+	}
+
+	switch filepath.Ext(path) {
+	case ".ts", ".cts", ".mts":
+		slog.WarnContext(ctx, fmt.Sprintf(`Unable to load shared config "%s"`, path))
+		slog.WarnContext(ctx, "TS shared configs are not supported.")
+		return map[string]any{}, errInvalidConfigFile
+	}
+
+	return loadConfigFileShared(ctx, path, evalJS, false)
+}
+
+// resolveSharedConfig returns the path of the file for the shared config ref,
+// following Node's resolution of relative paths and packages in node_modules
+// directories of dir and its parents.
+func resolveSharedConfig(dir string, ref string) (string, error) {
+	if strings.HasPrefix(ref, ".") || filepath.IsAbs(ref) {
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, ref)
+		}
+		if p := resolveModulePath(path, "."); p != "" {
+			return p, nil
+		}
+		return "", fmt.Errorf("runner: no config file at %s", path)
+	}
+
+	// A package name, possibly scoped, followed by an optional path within it.
+	name, sub := ref, ""
+	parts := strings.SplitN(ref, "/", 3)
+	switch {
+	case strings.HasPrefix(ref, "@") && len(parts) > 2:
+		name, sub = parts[0]+"/"+parts[1], parts[2]
+	case !strings.HasPrefix(ref, "@") && len(parts) > 1:
+		name, sub = parts[0], strings.Join(parts[1:], "/")
+	}
+
+	for d := dir; ; {
+		pkgDir := filepath.Join(d, "node_modules", filepath.FromSlash(name))
+		if fi, err := os.Stat(pkgDir); err == nil && fi.IsDir() {
+			if sub == "" {
+				if p := resolveModulePath(pkgDir, "."); p != "" {
+					return p, nil
+				}
+			} else if p := resolveModulePath(pkgDir, "./"+sub); p != "" {
+				return p, nil
+			}
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d || parent == "" {
+			return "", fmt.Errorf("runner: package %s not found in node_modules", name)
+		}
+		d = parent
+	}
+}
+
+// resolveModulePath resolves the module at path, a file or directory. For a
+// package directory, sub is the subpath within the package, with "." for its main
+// entry, which is looked up in the package's exports or main fields. An empty
+// string is returned if nothing is found.
+func resolveModulePath(path string, sub string) string {
+	if sub == "." {
+		if p := resolveModuleFile(path); p != "" {
+			return p
+		}
+	}
+
+	var pkg struct {
+		Exports any    `json:"exports"`
+		Main    string `json:"main"`
+	}
+	if b, err := os.ReadFile(filepath.Join(path, "package.json")); err == nil { //nolint:gosec
+		_ = json.Unmarshal(b, &pkg)
+	}
+	if target := packageExport(pkg.Exports, sub); target != "" {
+		if p := resolveModuleFile(filepath.Join(path, filepath.FromSlash(target))); p != "" {
+			return p
+		}
+	}
+
+	dir := path
+	if sub == "." {
+		if pkg.Main != "" {
+			if p := resolveModuleFile(filepath.Join(path, filepath.FromSlash(pkg.Main))); p != "" {
+				return p
+			}
+		}
+	} else {
+		dir = filepath.Join(path, filepath.FromSlash(sub))
+		if p := resolveModuleFile(dir); p != "" {
+			return p
+		}
+	}
+	return resolveModuleFile(filepath.Join(dir, "index"))
+}
+
+// resolveModuleFile returns path if it is a file, or path with one of
+// sharedConfigExtensions.
+func resolveModuleFile(path string) string {
+	if isFile(path) {
+		return path
+	}
+	for _, ext := range sharedConfigExtensions {
+		if isFile(path + ext) {
+			return path + ext
+		}
+	}
+	return ""
+}
+
+// packageExport returns the target of sub in the exports field of a package.json,
+// supporting the common forms used by shared configs: a string, a map of subpaths
+// and conditions.
+func packageExport(exports any, sub string) string {
+	switch e := exports.(type) {
+	case string:
+		if sub == "." {
+			return e
+		}
+	case map[string]any:
+		if t, ok := e[sub]; ok {
+			return exportTarget(t)
+		}
+		// A map of conditions for the main entry.
+		if sub == "." {
+			for k := range e {
+				if strings.HasPrefix(k, ".") {
+					return ""
+				}
+			}
+			return exportTarget(e)
+		}
+	}
+	return ""
+}
+
+// exportTarget returns the path of an export target, preferring the conditions
+// that apply when requiring a config.
+func exportTarget(t any) string {
+	switch t := t.(type) {
+	case string:
+		return t
+	case map[string]any:
+		for _, c := range []string{"prettier", "require", "node", "default", "import"} {
+			if v, ok := t[c]; ok {
+				if s := exportTarget(v); s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,289 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		exp   string
+	}{
+		{
+			name: "none",
+			exp:  "",
+		},
+		{
+			name: "package.json without prettier",
+			files: map[string]string{
+				"package.json": `{"name": "app"}`,
+			},
+			exp: "",
+		},
+		{
+			name: "package.json",
+			files: map[string]string{
+				"package.json":     `{"name": "app", "prettier": {"tabWidth": 4}}`,
+				"sub/package.json": `{"name": "sub"}`,
+			},
+			exp: "package.json",
+		},
+		{
+			name: "closest directory",
+			files: map[string]string{
+				".prettierrc":          `tabWidth: 2`,
+				"sub/dir/package.yaml": "prettier:\n  tabWidth: 4\n",
+			},
+			exp: "sub/dir/package.yaml",
+		},
+		{
+			name: "precedence in directory",
+			files: map[string]string{
+				"sub/dir/.prettierrc.toml":  `tabWidth = 4`,
+				"sub/dir/.prettierrc.json5": `{tabWidth: 4}`,
+			},
+			exp: "sub/dir/.prettierrc.json5",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "dir"), 0o755))
+			for name, content := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+
+			exp := ""
+			if tc.exp != "" {
+				exp = filepath.Join(dir, tc.exp)
+			}
+			r := &configResolver{dirs: map[string]string{}}
+			require.Equal(t, exp, r.configFile(filepath.Join(dir, "sub", "dir")))
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		path     string
+		exp      map[string]any
+		noEvalJS bool
+		invalid  bool
+	}{
+		{
+			name: "json5",
+			files: map[string]string{
+				".prettierrc.json5": `{tabWidth: 4, /* comment */ semi: false,}`,
+			},
+			path: ".prettierrc.json5",
+			exp:  map[string]any{"tabWidth": 4.0, "semi": false},
+		},
+		{
+			name: "jsonc",
+			files: map[string]string{
+				".prettierrc.jsonc": "{\n  // comment\n  \"tabWidth\": 4,\n}",
+			},
+			path: ".prettierrc.jsonc",
+			exp:  map[string]any{"tabWidth": 4.0},
+		},
+		{
+			name: "package.json",
+			files: map[string]string{
+				"package.json": `{"name": "app", "prettier": {"tabWidth": 4}}`,
+			},
+			path: "package.json",
+			exp:  map[string]any{"tabWidth": 4.0},
+		},
+		{
+			name: "package.yaml",
+			files: map[string]string{
+				"package.yaml": "name: app\nprettier:\n  tabWidth: 4\n",
+			},
+			path: "package.yaml",
+			exp:  map[string]any{"tabWidth": 4},
+		},
+		{
+			name: "package.json shared config",
+			files: map[string]string{
+				"package.json":         `{"name": "app", "prettier": "./config/prettier.json"}`,
+				"config/prettier.json": `{"tabWidth": 4}`,
+			},
+			path: "package.json",
+			exp:  map[string]any{"tabWidth": 4},
+		},
+		{
+			name: ".prettierrc shared config",
+			files: map[string]string{
+				".prettierrc":          `"./config/prettier.toml"`,
+				"config/prettier.toml": `tabWidth = 4`,
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"tabWidth": int64(4)},
+		},
+		{
+			name: "shared config package",
+			files: map[string]string{
+				".prettierrc": `"@acme/prettier-config"`,
+				"node_modules/@acme/prettier-config/package.json": `{"name": "@acme/prettier-config", "main": "config.yaml"}`,
+				"node_modules/@acme/prettier-config/config.yaml":  "tabWidth: 4\n",
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"tabWidth": 4},
+		},
+		{
+			name: "shared config package exports",
+			files: map[string]string{
+				"package.json":                      `{"name": "app", "prettier": "shared"}`,
+				"node_modules/shared/package.json":  `{"name": "shared", "exports": {".": {"import": "./esm.mjs", "default": "./prettier.json"}}}`,
+				"node_modules/shared/prettier.json": `{"semi": false}`,
+			},
+			path: "package.json",
+			exp:  map[string]any{"semi": false},
+		},
+		{
+			name: "shared config package subpath",
+			files: map[string]string{
+				".prettierrc": `"@acme/prettier-config/strict"`,
+				"node_modules/@acme/prettier-config/package.json": `{"name": "@acme/prettier-config"}`,
+				"node_modules/@acme/prettier-config/strict.toml":  "tabWidth = 8\n",
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"tabWidth": int64(8)},
+		},
+		{
+			name: "shared config package index in parent directory",
+			files: map[string]string{
+				"config/.prettierrc":            `"plain"`,
+				"node_modules/plain/index.json": `{"printWidth": 100}`,
+			},
+			path: "config/.prettierrc",
+			exp:  map[string]any{"printWidth": 100},
+		},
+		{
+			name: "shared config directory",
+			files: map[string]string{
+				".prettierrc":         `"./config"`,
+				"config/package.json": `{"main": "prettier.yml"}`,
+				"config/prettier.yml": "useTabs: true\n",
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"useTabs": true},
+		},
+		{
+			name: "js",
+			files: map[string]string{
+				"prettier.config.cjs": `module.exports = {"tabWidth": 4}`,
+			},
+			path: "prettier.config.cjs",
+			exp:  map[string]any{"tabWidth": float64(4)},
+		},
+		{
+			name: "js without evaluator",
+			files: map[string]string{
+				"prettier.config.cjs": `module.exports = {"tabWidth": 4}`,
+			},
+			path:     "prettier.config.cjs",
+			noEvalJS: true,
+			invalid:  true,
+		},
+		{
+			name: "shared config js",
+			files: map[string]string{
+				".prettierrc":                        `"jsconfig"`,
+				"node_modules/jsconfig/package.json": `{"name": "jsconfig"}`,
+				"node_modules/jsconfig/index.js":     `module.exports = {"semi": false}`,
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"semi": false},
+		},
+		{
+			name: "shared config ts",
+			files: map[string]string{
+				".prettierrc":                        `"tsconfig"`,
+				"node_modules/tsconfig/package.json": `{"main": "index.ts"}`,
+				"node_modules/tsconfig/index.ts":     `export default {}`,
+			},
+			path:    ".prettierrc",
+			invalid: true,
+		},
+		{
+			name: "shared config missing",
+			files: map[string]string{
+				".prettierrc": `"@acme/missing"`,
+			},
+			path:    ".prettierrc",
+			invalid: true,
+		},
+		{
+			name: "shared config referencing shared config",
+			files: map[string]string{
+				".prettierrc":          `"./config/prettier.json"`,
+				"config/prettier.json": `"./other.json"`,
+				"config/other.json":    `{"tabWidth": 4}`,
+			},
+			path:    ".prettierrc",
+			invalid: true,
+		},
+		{
+			name: "toml without extension",
+			files: map[string]string{
+				".prettierrc": "tabWidth = 4\nsemi = false\n",
+			},
+			path: ".prettierrc",
+			exp:  map[string]any{"tabWidth": int64(4), "semi": false},
+		},
+		{
+			name: "invalid",
+			files: map[string]string{
+				".prettierrc.json5": `{tabWidth: }`,
+			},
+			path:    ".prettierrc.json5",
+			invalid: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				p := filepath.Join(dir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+				require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+			}
+
+			// JS configs are evaluated by the wasm guest, so stand in for it with
+			// configs that are JSON after the export.
+			evalJS := func(_ context.Context, path string) (any, error) {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return nil, err
+				}
+				var v any
+				err = json.Unmarshal([]byte(strings.TrimPrefix(string(b), "module.exports = ")), &v)
+				return v, err
+			}
+			if tc.noEvalJS {
+				evalJS = nil
+			}
+
+			res, err := loadConfigFile(t.Context(), filepath.Join(dir, tc.path), evalJS)
+			if tc.invalid {
+				require.ErrorIs(t, err, errInvalidConfigFile)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, res)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// The daemon keeps a Runner, with its compiled module and warm instances, alive
+// between CLI invocations so that formatting stdin, as editors do on save, does
+// not pay startup cost each time. Clients connect over a Unix domain socket and
+// send one JSON request per connection, receiving one JSON response.
+
+// DefaultDaemonIdleTimeout is how long the daemon waits for a request before
+// shutting down.
+const DefaultDaemonIdleTimeout = 15 * time.Minute
+
+// daemonDialTimeout bounds connecting to the daemon so a wedged daemon falls back
+// to formatting in-process quickly.
+const daemonDialTimeout = 500 * time.Millisecond
+
+// ErrDaemonRunning is returned by ServeDaemon when a daemon for the same version is
+// already running.
+var ErrDaemonRunning = errors.New("runner: daemon already running")
+
+// daemonVersion identifies the build of go-prettier, including the prettier
+// bundle. Each version has its own socket so that a daemon started by a
+// different build is never used.
+var daemonVersion = sync.OnceValue(func() string {
+	v := bundleHash()
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		v = bi.Main.Version + "+" + v
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				v += "+" + s.Value
+			}
+		}
+	}
+	return v
+})
+
+// Values for daemonRequest.Name.
+const (
+	daemonRequestFormat  = "format"
+	daemonRequestParsers = "parsers"
+	daemonRequestPing    = "ping"
+	daemonRequestStop    = "stop"
+)
+
+type daemonRequest struct {
+	Name    string  `json:"name"`
+	Version string  `json:"version"`
+	Args    RunArgs `json:"args"`
+	Stdin   string  `json:"stdin,omitempty"`
+}
+
+type daemonResponse struct {
+	Version      string      `json:"version"`
+	Body         string      `json:"body"`
+	CursorOffset int         `json:"cursorOffset"`
+	Parsers      []string    `json:"parsers,omitempty"`
+	Logs         []daemonLog `json:"logs,omitempty"`
+	Error        *jsonError  `json:"error,omitempty"`
+}
+
+// daemonLog is a message logged while handling a request, which is sent to the
+// client to log as if it had formatted in-process.
+type daemonLog struct {
+	Level   slog.Level `json:"level"`
+	Message string     `json:"message"`
+}
+
+type daemonLogsKey struct{}
+
+// daemonLogs collects the messages logged while handling a request.
+type daemonLogs struct {
+	mu   sync.Mutex
+	logs []daemonLog
+}
+
+// NewDaemonLogHandler returns a slog.Handler for the daemon process that sends
+// messages logged while handling a request to its client instead of h.
+func NewDaemonLogHandler(h slog.Handler) slog.Handler {
+	return daemonLogHandler{h}
+}
+
+type daemonLogHandler struct {
+	slog.Handler
+}
+
+// Enabled implements slog.Handler. All messages are collected for a request
+// since the client filters them by its own level.
+func (h daemonLogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if _, ok := ctx.Value(daemonLogsKey{}).(*daemonLogs); ok {
+		return true
+	}
+	return h.Handler.Enabled(ctx, l)
+}
+
+// Handle implements slog.Handler.
+func (h daemonLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if logs, ok := ctx.Value(daemonLogsKey{}).(*daemonLogs); ok {
+		logs.mu.Lock()
+		logs.logs = append(logs.logs, daemonLog{Level: r.Level, Message: r.Message})
+		logs.mu.Unlock()
+		return nil
+	}
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck
+}
+
+// WithAttrs implements slog.Handler.
+func (h daemonLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return daemonLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h daemonLogHandler) WithGroup(name string) slog.Handler {
+	return daemonLogHandler{h.Handler.WithGroup(name)}
+}
+
+// DaemonSocketPath returns the path of the socket the daemon listens on, which is
+// in the user cache directory so that each user has their own daemon. The name
+// includes a hash of the version, which is too long for socket paths as is, so
+// that daemons of different versions can run side by side.
+func DaemonSocketPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	h := sha256.Sum256([]byte(daemonVersion()))
+	name := "daemon-" + hex.EncodeToString(h[:8]) + ".sock"
+	return filepath.Join(dir, "com.github.wasilibs", "prettier", name)
+}
+
+// ServeDaemon listens on socketPath and handles requests from clients until ctx
+// is done or no request has been received for idleTimeout.
+func (r *Runner) ServeDaemon(ctx context.Context, socketPath string, idleTimeout time.Duration) error {
+	if _, err := requestDaemon(ctx, socketPath, daemonRequest{Name: daemonRequestPing}); err == nil {
+		return ErrDaemonRunning
+	}
+	// The socket file is left behind if a daemon is killed.
+	_ = os.Remove(socketPath)
+
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o700); err != nil {
+		return fmt.Errorf("runner: creating daemon socket directory: %w", err)
+	}
+	var lc net.ListenConfig
+	l, err := lc.Listen(ctx, "unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("runner: listening on daemon socket: %w", err)
+	}
+	// Closing the listener also removes the socket file.
+	defer l.Close()
+
+	slog.InfoContext(ctx, "Listening on "+socketPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	activity := make(chan struct{}, 1)
+	go func() {
+		timer := time.NewTimer(idleTimeout)
+		defer timer.Stop()
+		for {
+			select {
+			case <-activity:
+				timer.Reset(idleTimeout)
+			case <-timer.C:
+				slog.InfoContext(ctx, "Shutting down after being idle")
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("runner: accepting daemon connection: %w", err)
+		}
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if stop := r.handleDaemonConn(ctx, conn); stop {
+				cancel()
+			}
+		}()
+	}
+}
+
+// handleDaemonConn handles a request on conn. It returns true if the daemon should
+// stop because it was requested.
+func (r *Runner) handleDaemonConn(ctx context.Context, conn net.Conn) bool {
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		slog.DebugContext(ctx, fmt.Sprintf("Invalid daemon request: %v", err))
+		return false
+	}
+
+	res := daemonResponse{Version: daemonVersion()}
+	stop := false
+	switch {
+	case req.Name == daemonRequestStop:
+		slog.InfoContext(ctx, "Shutting down on request")
+		stop = true
+	case req.Version != daemonVersion():
+		// Only possible when a socket is shared by mistake. The client checks the
+		// version of the response and falls back.
+		res.Error = &jsonError{Name: "Error", Message: "daemon is a different version"}
+	case req.Name == daemonRequestPing:
+	case req.Name == daemonRequestParsers:
+		parsers, err := r.Parsers(ctx)
+		if err != nil {
+			res.Error = &jsonError{Name: "Error", Message: err.Error()}
+		}
+		res.Parsers = parsers
+	case req.Name == daemonRequestFormat:
+		logs := &daemonLogs{}
+		res.Body, res.CursorOffset, res.Error = r.handleDaemonFormat(context.WithValue(ctx, daemonLogsKey{}, logs), req)
+		res.Logs = logs.logs
+	default:
+		res.Error = &jsonError{Name: "Error", Message: fmt.Sprintf("unknown request %q", req.Name)}
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		slog.DebugContext(ctx, fmt.Sprintf("Writing daemon response: %v", err))
+	}
+	return stop
+}
+
+func (r *Runner) handleDaemonFormat(ctx context.Context, req daemonRequest) (string, int, *jsonError) {
+	// Config is resolved fresh for each request so that edits to config files apply.
+	resolver, err := newConfigResolver(ctx, req.Args, r)
+	if err != nil {
+		return "", 0, &jsonError{Name: "Error", Message: err.Error()}
+	}
+	res, cursorOffset, err := r.formatStdin(ctx, req.Args, resolver, []byte(req.Stdin))
+	if err == nil {
+		return res, cursorOffset, nil
+	}
+
+	var se *SyntaxError
+	switch {
+	case errors.Is(err, ErrNoParser):
+		return "", 0, &jsonError{Name: "UndefinedParserError", Message: err.Error()}
+	case errors.As(err, &se):
+		return "", 0, &jsonError{Name: "SyntaxError", Message: se.Message, Loc: se.Loc}
+	}
+	return "", 0, &jsonError{Name: "Error", Message: err.Error()}
+}
+
+// StopDaemon stops the daemon listening on socketPath. It returns false if no
+// daemon is running.
+func StopDaemon(ctx context.Context, socketPath string) bool {
+	_, err := requestDaemon(ctx, socketPath, daemonRequest{Name: daemonRequestStop})
+	return err == nil
+}
+
+// DaemonParsers returns the parsers supported by a running daemon for the same
+// version, for validating flags without creating a runner. It returns false if
+// there is none.
+func DaemonParsers(ctx context.Context) ([]string, bool) {
+	res, err := requestDaemon(ctx, DaemonSocketPath(), daemonRequest{Name: daemonRequestParsers})
+	if err != nil || res.Version != daemonVersion() || res.Error != nil {
+		return nil, false
+	}
+	return res.Parsers, true
+}
+
+// RunWithDaemon formats stdin as Run does using a running daemon. Only stdin is
+// forwarded. It returns false if args formats files rather than stdin or if there
+// is no daemon for the same version running, in which case the caller should
+// format in-process instead.
+func RunWithDaemon(ctx context.Context, args RunArgs) (bool, error) {
+	if !args.Stdin && args.StdinFilepath == "" {
+		return false, nil
+	}
+	if args.StdinFilepath == "" && args.Parser == "" {
+		// Let Run report the error.
+		return false, nil
+	}
+
+	// Paths are resolved by the daemon, which has a different working directory.
+	cwd, err := filepath.Abs(args.Cwd)
+	if err != nil {
+		return false, nil //nolint:nilerr // Fall back to formatting in-process
+	}
+	args.Cwd = cwd
+	if args.Config != "" && !filepath.IsAbs(args.Config) {
+		args.Config = filepath.Join(cwd, args.Config)
+	}
+
+	// Make sure a daemon is listening before consuming stdin.
+	conn, err := dialDaemon(ctx, DaemonSocketPath())
+	if err != nil {
+		return false, nil //nolint:nilerr // Fall back to formatting in-process
+	}
+	defer conn.Close()
+
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return true, fmt.Errorf("runner: reading stdin: %w", err)
+	}
+
+	res, err := requestDaemonConn(conn, daemonRequest{
+		Name:  daemonRequestFormat,
+		Args:  args,
+		Stdin: string(in),
+	})
+	if err != nil || res.Version != daemonVersion() {
+		// stdin has been consumed so format in-process here rather than returning.
+		if err != nil {
+			slog.DebugContext(ctx, fmt.Sprintf("Daemon request failed, formatting in-process: %v", err))
+		}
+		return true, runInProcess(ctx, args, in)
+	}
+
+	for _, l := range res.Logs {
+		slog.Log(ctx, l.Level, l.Message)
+	}
+
+	var formatErr error
+	if e := res.Error; e != nil {
+		switch e.Name {
+		case "UndefinedParserError":
+			formatErr = ErrNoParser
+		case "SyntaxError":
+			formatErr = &SyntaxError{Path: args.StdinFilepath, Message: e.Message, Loc: e.Loc}
+		default:
+			formatErr = errors.New(e.Message)
+		}
+	}
+	return true, writeStdinResult(ctx, args, in, res.Body, res.CursorOffset, formatErr)
+}
+
+// runInProcess formats in, already read from stdin, without the daemon.
+func runInProcess(ctx context.Context, args RunArgs, in []byte) error {
+	r := NewRunner()
+	defer func() { _ = r.Close(ctx) }()
+
+	resolver, err := newConfigResolver(ctx, args, r)
+	if err != nil {
+		return err
+	}
+	res, cursorOffset, err := r.formatStdin(ctx, args, resolver, in)
+	return writeStdinResult(ctx, args, in, res, cursorOffset, err)
+}
+
+func dialDaemon(ctx context.Context, socketPath string) (net.Conn, error) {
+	d := net.Dialer{Timeout: daemonDialTimeout}
+	conn, err := d.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("runner: connecting to daemon: %w", err)
+	}
+	return conn, nil
+}
+
+func requestDaemon(ctx context.Context, socketPath string, req daemonRequest) (daemonResponse, error) {
+	conn, err := dialDaemon(ctx, socketPath)
+	if err != nil {
+		return daemonResponse{}, err
+	}
+	defer conn.Close()
+	return requestDaemonConn(conn, req)
+}
+
+func requestDaemonConn(conn net.Conn, req daemonRequest) (daemonResponse, error) {
+	req.Version = daemonVersion()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return daemonResponse{}, fmt.Errorf("runner: sending daemon request: %w", err)
+	}
+	var res daemonResponse
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&res); err != nil {
+		return daemonResponse{}, fmt.Errorf("runner: reading daemon response: %w", err)
+	}
+	return res, nil
+}
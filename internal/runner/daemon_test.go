@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemon(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "d.sock")
+
+	serve := func(idleTimeout time.Duration) chan error {
+		done := make(chan error, 1)
+		go func() {
+			// Requests that don't format don't need a compiled module.
+			done <- (&Runner{}).ServeDaemon(t.Context(), socketPath, idleTimeout)
+		}()
+		require.Eventually(t, func() bool {
+			_, err := requestDaemon(t.Context(), socketPath, daemonRequest{Name: daemonRequestPing})
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		return done
+	}
+
+	t.Run("ping and stop", func(t *testing.T) {
+		done := serve(time.Minute)
+
+		res, err := requestDaemon(t.Context(), socketPath, daemonRequest{Name: daemonRequestPing})
+		require.NoError(t, err)
+		require.Equal(t, daemonVersion(), res.Version)
+
+		// Another daemon for the same version isn't started.
+		require.ErrorIs(t, (&Runner{}).ServeDaemon(t.Context(), socketPath, time.Minute), ErrDaemonRunning)
+
+		require.True(t, StopDaemon(t.Context(), socketPath))
+		require.NoError(t, <-done)
+		require.False(t, StopDaemon(t.Context(), socketPath))
+	})
+
+	t.Run("different version", func(t *testing.T) {
+		done := serve(time.Minute)
+
+		conn, err := dialDaemon(t.Context(), socketPath)
+		require.NoError(t, err)
+		defer conn.Close()
+		// requestDaemonConn always sends our version so write the request directly.
+		_, err = conn.Write([]byte(`{"name":"format","version":"other","args":{}}` + "\n"))
+		require.NoError(t, err)
+
+		var res daemonResponse
+		require.NoError(t, json.NewDecoder(conn).Decode(&res))
+		require.Equal(t, daemonVersion(), res.Version)
+		require.NotNil(t, res.Error)
+
+		// The daemon keeps running for clients of its own version.
+		_, err = requestDaemon(t.Context(), socketPath, daemonRequest{Name: daemonRequestPing})
+		require.NoError(t, err)
+		require.True(t, StopDaemon(t.Context(), socketPath))
+		require.NoError(t, <-done)
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		done := serve(100 * time.Millisecond)
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("daemon did not shut down when idle")
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan error, 1)
+		go func() {
+			done <- (&Runner{}).ServeDaemon(ctx, socketPath, time.Minute)
+		}()
+		require.Eventually(t, func() bool {
+			_, err := requestDaemon(t.Context(), socketPath, daemonRequest{Name: daemonRequestPing})
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+func TestDaemonSocketPath(t *testing.T) {
+	p := DaemonSocketPath()
+	require.Regexp(t, `daemon-[0-9a-f]{16}\.sock$`, p)
+	require.Equal(t, p, DaemonSocketPath())
+}
+
+func TestDaemonLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewDaemonLogHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	logs := &daemonLogs{}
+	ctx := context.WithValue(t.Context(), daemonLogsKey{}, logs)
+	logger.WarnContext(ctx, "for the client")
+	logger.DebugContext(ctx, "debug for the client")
+	logger.ErrorContext(t.Context(), "for the daemon")
+
+	require.Equal(t, []daemonLog{
+		{Level: slog.LevelWarn, Message: "for the client"},
+		{Level: slog.LevelDebug, Message: "debug for the client"},
+	}, logs.logs)
+	require.Equal(t, "for the daemon\n", logMessages(t, &buf))
+}
@@ -0,0 +1,306 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wasilibs/go-prettier/v3/internal/color"
+)
+
+// DefaultDiffContext is the number of unchanged lines shown around each change
+// in a diff, matching diff -u and git.
+const DefaultDiffContext = 3
+
+type diffOp byte
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// unifiedDiff returns a unified diff from a to b for the file at path, with
+// context unchanged lines around each change. An empty string is returned if
+// there are no differences.
+func unifiedDiff(path string, a, b string, context int, noColor bool) string {
+	if a == b {
+		return ""
+	}
+
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	sb.WriteString(color.Colorize(color.Bold, "--- a/"+path, noColor))
+	sb.WriteByte('\n')
+	sb.WriteString(color.Colorize(color.Bold, "+++ b/"+path, noColor))
+	sb.WriteByte('\n')
+
+	for _, h := range diffHunks(lines, context) {
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.aStart, h.aLen), hunkRange(h.bStart, h.bLen))
+		sb.WriteString(color.Colorize(color.Cyan, header, noColor))
+		sb.WriteByte('\n')
+		for _, l := range h.lines {
+			text, noEOL := strings.CutSuffix(l.text, "\n")
+			noEOL = !noEOL
+			switch l.op {
+			case diffEqual:
+				sb.WriteString(" " + text)
+			case diffDelete:
+				sb.WriteString(color.Colorize(color.Red, "-"+text, noColor))
+			case diffInsert:
+				sb.WriteString(color.Colorize(color.Green, "+"+text, noColor))
+			}
+			sb.WriteByte('\n')
+			if noEOL {
+				sb.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// splitLines splits s into lines, each keeping its trailing newline so that a
+// missing newline at the end of the content is a difference.
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script from a to b using Myers' algorithm,
+// in the linear space variant that recursively splits the problem at the middle
+// snake of an optimal path so that rewriting a large file does not need memory
+// proportional to the number of lines times the number of edits.
+// http://www.xmailserver.org/diff2.pdf
+func diffLines(a, b []string) []diffLine {
+	res := appendDiff(nil, a, b)
+
+	// Splitting can interleave deletions and insertions within a change, so
+	// order each run of changes with deletions first like diff -u.
+	for i := 0; i < len(res); {
+		if res[i].op == diffEqual {
+			i++
+			continue
+		}
+		end := i
+		for end < len(res) && res[end].op != diffEqual {
+			end++
+		}
+		slices.SortStableFunc(res[i:end], func(x, y diffLine) int {
+			return cmp.Compare(x.op, y.op)
+		})
+		i = end
+	}
+	return res
+}
+
+func appendDiff(res []diffLine, a, b []string) []diffLine {
+	// Common prefixes and suffixes are trivially part of the script and trimming
+	// them ensures each split makes progress.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		res = append(res, diffLine{op: diffEqual, text: a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			res = append(res, diffLine{op: diffInsert, text: l})
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			res = append(res, diffLine{op: diffDelete, text: l})
+		}
+	default:
+		x, y := middleSnake(a, b)
+		res = appendDiff(res, a[:x], b[:y])
+		res = appendDiff(res, a[x:], b[y:])
+	}
+
+	for _, l := range common {
+		res = append(res, diffLine{op: diffEqual, text: l})
+	}
+	return res
+}
+
+// middleSnake returns a point on a shortest edit path from a to b, found by
+// searching forwards from the start and backwards from the end until the paths
+// overlap. a and b must be non-empty and differ in their first and last lines.
+func middleSnake(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// vf and vb hold the furthest x reached on each diagonal k by the forward
+	// and backward searches, with x and k of the backward search measured from
+	// the end.
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta, the paths can only overlap after a forward step.
+	front := delta%2 != 0
+	// Diagonals that have run off the edge of the edit graph are skipped.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if bk := offset + delta - k; bk >= 0 && bk < len(vb) && vb[bk] != -1 && x >= n-vb[bk] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				if fk := offset + delta - k; fk >= 0 && fk < len(vf) && vf[fk] != -1 && vf[fk] >= n-x {
+					fx := vf[fk]
+					return fx, offset + fx - fk
+				}
+			}
+		}
+	}
+
+	// Not reachable for valid input since the paths must overlap by maxD, but
+	// fall back to replacing everything.
+	return n, 0
+}
+
+type diffHunk struct {
+	aStart, aLen int
+	bStart, bLen int
+	lines        []diffLine
+}
+
+// diffHunks groups changes in lines into hunks with context unchanged lines
+// around them, merging hunks whose context would overlap.
+func diffHunks(lines []diffLine, context int) []diffHunk {
+	var hunks []diffHunk
+
+	// Line numbers in a and b before lines[i].
+	aLine, bLine := 0, 0
+	aLines := make([]int, len(lines)+1)
+	bLines := make([]int, len(lines)+1)
+	for i, l := range lines {
+		aLines[i], bLines[i] = aLine, bLine
+		if l.op != diffInsert {
+			aLine++
+		}
+		if l.op != diffDelete {
+			bLine++
+		}
+	}
+	aLines[len(lines)], bLines[len(lines)] = aLine, bLine
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffEqual {
+				end++
+				continue
+			}
+			// Find the end of this run of unchanged lines.
+			next := end
+			for next < len(lines) && lines[next].op == diffEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = next
+		}
+
+		h := diffHunk{
+			aStart: aLines[start],
+			aLen:   aLines[end] - aLines[start],
+			bStart: bLines[start],
+			bLen:   bLines[end] - bLines[start],
+			lines:  lines[start:end],
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+
+	return hunks
+}
+
+// hunkRange formats the 0-based start and length of a hunk as in a unified diff
+// header, where an empty range refers to the line before it.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
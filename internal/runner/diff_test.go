@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/color"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		context int
+		exp     string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			exp:  "",
+		},
+		{
+			name:    "change",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:       "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			context: 3,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -2,7 +2,7 @@\n" +
+				" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:       "one\n2\n3\n4\n5\n6\n7\n8\nnine\n",
+			context: 1,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-1\n+one\n 2\n" +
+				"@@ -8,2 +8,2 @@\n" +
+				" 8\n-9\n+nine\n",
+		},
+		{
+			name:    "merged hunks",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "one\n2\n3\n4\nfive\n",
+			context: 2,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -1,5 +1,5 @@\n" +
+				"-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+		{
+			name:    "insert only",
+			a:       "1\n2\n",
+			b:       "1\n2\n3\n",
+			context: 0,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -2,0 +3 @@\n" +
+				"+3\n",
+		},
+		{
+			name:    "missing newline",
+			a:       "{\"a\":1}",
+			b:       "{ \"a\": 1 }\n",
+			context: 3,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -1 +1 @@\n" +
+				"-{\"a\":1}\n\\ No newline at end of file\n+{ \"a\": 1 }\n",
+		},
+		{
+			name:    "from empty",
+			a:       "",
+			b:       "a\n",
+			context: 3,
+			exp: "--- a/a.txt\n+++ b/a.txt\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+a\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, unifiedDiff("a.txt", tc.a, tc.b, tc.context, true))
+		})
+	}
+}
+
+func TestUnifiedDiffColor(t *testing.T) {
+	res := unifiedDiff("a.txt", "a\n", "b\n", 3, false)
+	require.True(t, strings.Contains(res, color.Colorize(color.Red, "-a", false)))
+	require.True(t, strings.Contains(res, color.Colorize(color.Green, "+b", false)))
+	require.True(t, strings.Contains(res, color.Colorize(color.Cyan, "@@ -1 +1 @@", false)))
+}
+
+func TestDiffLines(t *testing.T) {
+	a := splitLines("a\nb\nc\na\nb\nb\na\n")
+	b := splitLines("c\nb\na\nb\na\nc\n")
+
+	// Applying the script to a must give b.
+	edits := checkDiffLines(t, a, b, diffLines(a, b))
+	// The example from the Myers paper has a shortest edit script of length 5.
+	require.Equal(t, 5, edits)
+}
+
+// checkDiffLines verifies that lines is an edit script from a to b and returns
+// the number of edits.
+func checkDiffLines(t *testing.T, a, b []string, lines []diffLine) int {
+	t.Helper()
+	var gotA, gotB []string
+	edits := 0
+	for _, l := range lines {
+		if l.op != diffInsert {
+			gotA = append(gotA, l.text)
+		}
+		if l.op != diffDelete {
+			gotB = append(gotB, l.text)
+		}
+		if l.op != diffEqual {
+			edits++
+		}
+	}
+	require.Equal(t, a, gotA)
+	require.Equal(t, b, gotB)
+	return edits
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	// The shortest edit script has len(a) + len(b) - 2 * LCS edits.
+	lcs := func(a, b []string) int {
+		dp := make([][]int, len(a)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					dp[i][j] = dp[i+1][j+1] + 1
+				} else {
+					dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+				}
+			}
+		}
+		return dp[0][0]
+	}
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	gen := func() []string {
+		lines := make([]string, 1+rnd.IntN(20))
+		for i := range lines {
+			lines[i] = string(rune('a'+rnd.IntN(4))) + "\n"
+		}
+		return lines
+	}
+	for range 500 {
+		a, b := gen(), gen()
+		edits := checkDiffLines(t, a, b, diffLines(a, b))
+		require.Equal(t, len(a)+len(b)-2*lcs(a, b), edits, "a: %q, b: %q", a, b)
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	// Re-indenting a large file changes every line, the worst case for memory.
+	var a, b []string
+	for i := range 5000 {
+		a = append(a, fmt.Sprintf("  line %d\n", i))
+		b = append(b, fmt.Sprintf("    line %d\n", i))
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lines := diffLines(a, b)
+	runtime.ReadMemStats(&after)
+
+	require.Equal(t, len(a)+len(b), checkDiffLines(t, a, b, lines))
+	// Storing the search state of every round would need gigabytes.
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<20))
+}
+
+func BenchmarkDiffLines(b *testing.B) {
+	var x, y []string
+	for i := range 5000 {
+		x = append(x, fmt.Sprintf("line %d\n", i))
+		if i%10 == 0 {
+			y = append(y, fmt.Sprintf("changed %d\n", i))
+		} else {
+			y = append(y, fmt.Sprintf("line %d\n", i))
+		}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		diffLines(x, y)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/bmatcuk/doublestar/v4"
 
+	"github.com/wasilibs/go-prettier/v3/internal/git"
 	"github.com/wasilibs/go-prettier/v3/internal/gitignore"
 )
 
@@ -40,50 +41,8 @@ func expandPatterns(ctx context.Context, args RunArgs) []expandedPath {
 
 	var expanded []expandedPattern
 
-	base, _ := filepath.Abs(args.Cwd)
-
-	var ignores []gitignore.Matcher
-	for _, p := range args.IgnorePaths {
-		// Unlike upstream, we try to match git behavior better by
-		// finding all .gitignore files in the repository. Notably,
-		// this will find the root one when working in a subdirectory.
-		if p == ".gitignore" {
-			dir := base
-			for {
-				if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
-					if ps, err := gitignore.ReadPatterns(dir); err == nil {
-						ignores = append(ignores, gitignore.NewMatcher(ps))
-					} else {
-						slog.DebugContext(ctx, fmt.Sprintf("Error loading .gitignore: %v", err))
-					}
-					break
-				}
-
-				parent := filepath.Dir(dir)
-				if parent == dir || parent == "" {
-					break
-				}
-
-				dir = parent
-			}
-
-			continue
-		}
-
-		abs, _ := filepath.Abs(p)
-		if ps, err := gitignore.ReadIgnoreFile(filepath.Dir(abs), filepath.Base(abs)); err == nil {
-			ignores = append(ignores, gitignore.NewMatcher(ps))
-		}
-	}
-
-	var customIgnores []gitignore.Pattern
-	customIgnores = append(customIgnores, gitignore.ParsePattern(".git", nil))
-	customIgnores = append(customIgnores, gitignore.ParsePattern(".sl", nil))
-	customIgnores = append(customIgnores, gitignore.ParsePattern(".svn", nil))
-	customIgnores = append(customIgnores, gitignore.ParsePattern(".hg", nil))
-	if !args.WithNodeModules {
-		customIgnores = append(customIgnores, gitignore.ParsePattern("node_modules", nil))
-	}
+	ignores := loadIgnoreFiles(ctx, args)
+	customIgnores := defaultIgnorePatterns(args)
 
 	for _, pattern := range args.Patterns {
 		pattern = filepath.Join(args.Cwd, pattern)
@@ -182,6 +141,66 @@ func expandPatterns(ctx context.Context, args RunArgs) []expandedPath {
 	return res
 }
 
+// loadIgnoreFiles reads the ignore files specified in args.
+func loadIgnoreFiles(ctx context.Context, args RunArgs) []gitignore.Matcher {
+	base, _ := filepath.Abs(args.Cwd)
+
+	// Ignore files in the default locations are found throughout the repository,
+	// or the current directory if it is not in one.
+	var repo *git.Repo
+	root := base
+	if r, err := git.Find(base); err == nil {
+		repo = r
+		root = r.Root
+	} else {
+		slog.DebugContext(ctx, fmt.Sprintf("Not in a git repository: %v", err))
+	}
+
+	var ignores []gitignore.Matcher
+	for _, p := range args.IgnorePaths {
+		switch p {
+		case ".gitignore":
+			// Unlike upstream, we try to match git behavior better by
+			// using all .gitignore files in the repository, as well as
+			// .git/info/exclude and the global excludes file. Notably,
+			// this will find the root one when working in a subdirectory.
+			if repo != nil {
+				ignores = append(ignores, gitignore.NewRepoMatcher(repo.Root, p, repo.ExcludesFiles()))
+			}
+			continue
+		case ".prettierignore":
+			// Like .gitignore, so that running from a subdirectory respects the
+			// root .prettierignore and subprojects can have their own.
+			ignores = append(ignores, gitignore.NewRepoMatcher(root, p, nil))
+			continue
+		}
+
+		// Patterns in other ignore files are relative to the file's directory.
+		abs := p
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(base, p)
+		}
+		if ps, err := gitignore.ReadIgnoreFile(filepath.Dir(abs), filepath.Base(abs)); err == nil {
+			ignores = append(ignores, gitignore.NewMatcher(ps))
+		}
+	}
+
+	return ignores
+}
+
+// defaultIgnorePatterns returns the patterns for paths that are always ignored.
+func defaultIgnorePatterns(args RunArgs) []gitignore.Pattern {
+	var customIgnores []gitignore.Pattern
+	customIgnores = append(customIgnores, gitignore.ParsePattern(".git", nil))
+	customIgnores = append(customIgnores, gitignore.ParsePattern(".sl", nil))
+	customIgnores = append(customIgnores, gitignore.ParsePattern(".svn", nil))
+	customIgnores = append(customIgnores, gitignore.ParsePattern(".hg", nil))
+	if !args.WithNodeModules {
+		customIgnores = append(customIgnores, gitignore.ParsePattern("node_modules", nil))
+	}
+	return customIgnores
+}
+
 func ignoreAnyMatch(path string, ignores []gitignore.Matcher, isDir bool) bool {
 	path, _ = filepath.Abs(path)
 	parts := strings.Split(path, string(filepath.Separator))
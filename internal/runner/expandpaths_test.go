@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPatternsIgnoreFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".git/info/exclude":         "excluded.md\n",
+		".gitignore":                "gitignored/\n",
+		".prettierignore":           "root-ignored.md\n",
+		"a.md":                      "",
+		"root-ignored.md":           "",
+		"excluded.md":               "",
+		"gitignored/a.md":           "",
+		"sub/.prettierignore":       "sub-ignored.md\n/anchored.md\n",
+		"sub/a.md":                  "",
+		"sub/root-ignored.md":       "",
+		"sub/sub-ignored.md":        "",
+		"sub/anchored.md":           "",
+		"sub/deep/anchored.md":      "",
+		"sub/deep/sub-ignored.md":   "",
+		"other/sub-ignored.md":      "",
+		"config/custom-ignore":      "a.md\n",
+		"config/a.md":               "",
+		"config/nested/a.md":        "",
+		"config/nested/b.md":        "",
+		"gitignored/sub/.gitignore": "!*\n",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	tests := []struct {
+		name        string
+		cwd         string
+		ignorePaths []string
+		expected    []string
+	}{
+		{
+			name:        "root",
+			cwd:         ".",
+			ignorePaths: []string{".gitignore", ".prettierignore"},
+			expected: []string{
+				"a.md",
+				"config/a.md",
+				"config/nested/a.md",
+				"config/nested/b.md",
+				"other/sub-ignored.md",
+				"sub/a.md",
+				"sub/deep/anchored.md",
+			},
+		},
+		{
+			name:        "subdirectory",
+			cwd:         "sub",
+			ignorePaths: []string{".gitignore", ".prettierignore"},
+			expected: []string{
+				"sub/a.md",
+				"sub/deep/anchored.md",
+			},
+		},
+		{
+			name:        "custom ignore path relative to cwd",
+			cwd:         "config",
+			ignorePaths: []string{"custom-ignore"},
+			expected: []string{
+				"config/nested/b.md",
+			},
+		},
+		{
+			name:        "custom ignore path in parent",
+			cwd:         "config/nested",
+			ignorePaths: []string{"../custom-ignore"},
+			expected: []string{
+				"config/nested/b.md",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cwd := filepath.Join(dir, tc.cwd)
+			paths := expandPatterns(context.Background(), RunArgs{
+				Cwd:         cwd,
+				Patterns:    []string{"."},
+				IgnorePaths: tc.ignorePaths,
+			})
+			var res []string
+			for _, p := range paths {
+				require.Empty(t, p.error)
+				// Ignore files are expanded too but have no parser.
+				if filepath.Ext(p.filePath) != ".md" {
+					continue
+				}
+				rel, err := filepath.Rel(dir, p.filePath)
+				require.NoError(t, err)
+				res = append(res, filepath.ToSlash(rel))
+			}
+			sort.Strings(res)
+			require.Equal(t, tc.expected, res)
+		})
+	}
+}
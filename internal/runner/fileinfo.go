@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/wasilibs/go-prettier/v3/internal/gitignore"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/cli/find-config-path.js
+// https://github.com/prettier/prettier/blob/main/src/cli/file-info.js
+// https://github.com/prettier/prettier/blob/main/src/cli/print-config.js
+
+// InferParser returns the parser prettier infers from filePath, or an empty string
+// if none could be inferred.
+func (r *Runner) InferParser(ctx context.Context, filePath string) (string, error) {
+	msg, err := r.pool.request(ctx, jsonMsg{
+		Name: "infer-parser",
+		Body: filePath,
+	})
+	if err != nil {
+		return "", fmt.Errorf("runner: failed to run prettier [%s]: %w", filePath, err)
+	}
+	if msg.Name != "result" {
+		return "", fmt.Errorf("runner: unexpected message from prettier [%s]: %s", filePath, msg.Name)
+	}
+	return msg.Body, nil
+}
+
+func (r *Runner) findConfigPath(ctx context.Context, args RunArgs, resolver *configResolver) error {
+	absPath, err := filepath.Abs(filepath.Join(args.Cwd, args.FindConfigPath))
+	if err != nil {
+		return fmt.Errorf("runner: resolving path: %w", err)
+	}
+
+	p := resolver.configFile(filepath.Dir(absPath))
+	if p == "" {
+		err := fmt.Errorf(`Can not find configure file for "%s".`, args.FindConfigPath) //nolint:staticcheck // Match upstream message
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	base, _ := filepath.Abs(args.Cwd)
+	if rel, err := filepath.Rel(base, p); err == nil {
+		p = rel
+	}
+	fmt.Println(filepath.ToSlash(p))
+	return nil
+}
+
+type fileInfo struct {
+	Ignored        bool    `json:"ignored"`
+	InferredParser *string `json:"inferredParser"`
+}
+
+func (r *Runner) fileInfo(ctx context.Context, args RunArgs, resolver *configResolver) error {
+	path := filepath.Join(args.Cwd, args.FileInfo)
+
+	var info fileInfo
+
+	ignores := loadIgnoreFiles(ctx, args)
+	ignores = append(ignores, gitignore.NewMatcher(defaultIgnorePatterns(args)))
+	info.Ignored = ignoreAnyMatch(path, ignores, false)
+
+	if !info.Ignored {
+		cfg, err := resolver.resolve(ctx, path)
+		if err != nil {
+			return err
+		}
+		parser, _ := cfg["parser"].(string)
+		if parser == "" {
+			parser, err = r.InferParser(ctx, path)
+			if err != nil {
+				slog.ErrorContext(ctx, err.Error())
+				return err
+			}
+		}
+		if parser != "" {
+			info.InferredParser = &parser
+		}
+	}
+
+	infoBytes, err := json.Marshal(info)
+	if err != nil {
+		// Programming bug
+		panic(err)
+	}
+
+	return r.printJSON(ctx, infoBytes)
+}
+
+func (r *Runner) printConfig(ctx context.Context, args RunArgs, resolver *configResolver) error {
+	cfg, err := resolver.resolve(ctx, filepath.Join(args.Cwd, args.PrintConfig))
+	if err != nil {
+		return err
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		err = fmt.Errorf("runner: encoding config: %w", err)
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	return r.printJSON(ctx, cfgBytes)
+}
+
+// printJSON prints JSON to stdout. Like upstream, it is formatted by prettier itself.
+func (r *Runner) printJSON(ctx context.Context, in []byte) error {
+	res, err := r.formatContent(ctx, in, map[string]any{"parser": "json"})
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+	fmt.Print(res)
+	return nil
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/config/prettier-config/loaders.js
+
+// jsModuleExtensions are tried in order when a relative require has no
+// extension, like Node's module resolution.
+var jsModuleExtensions = []string{".js", ".cjs", ".mjs", ".json"}
+
+// isJSConfig returns whether the config file at path is JavaScript, which is
+// evaluated by the guest rather than parsed.
+func isJSConfig(path string) bool {
+	switch filepath.Ext(path) {
+	case ".js", ".cjs", ".mjs":
+		return true
+	}
+	return false
+}
+
+// jsEvaluator evaluates the JS config file at path, returning the exported config.
+type jsEvaluator func(ctx context.Context, path string) (any, error)
+
+// evalJSConfig evaluates the JS config file at path in a prettier instance,
+// returning the exported config. The guest has no filesystem access so the
+// modules it requires are read by us, and only for relative requires from the
+// config file or modules already loaded.
+func (r *Runner) evalJSConfig(ctx context.Context, path string) (any, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("runner: resolving config path: %w", err)
+	}
+	src, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("runner: reading config file: %w", err)
+	}
+
+	modules := &moduleLoader{loaded: map[string]bool{path: true}}
+	msg, err := r.pool.requestIsolated(ctx, jsonMsg{Name: "eval-config", Body: string(src), Path: path, ModuleType: jsModuleType(path)}, modules)
+	if err != nil {
+		return nil, fmt.Errorf("runner: failed to run prettier: %w", err)
+	}
+	switch msg.Name {
+	case "result":
+	case "error":
+		if msg.Error != nil {
+			return nil, errors.New(msg.Error.Message)
+		}
+		return nil, errors.New("runner: evaluating config failed")
+	default:
+		return nil, fmt.Errorf("runner: unexpected message from prettier: %s", msg.Name)
+	}
+
+	var res any
+	if err := json.Unmarshal([]byte(msg.Body), &res); err != nil {
+		return nil, fmt.Errorf("runner: unmarshaling config: %w", err)
+	}
+	return res, nil
+}
+
+// moduleLoader serves module requests from the guest while evaluating a JS
+// config.
+type moduleLoader struct {
+	mu sync.Mutex
+	// loaded are the paths of modules that have been sent to the guest, which
+	// are the only ones it may require relative to.
+	loaded map[string]bool
+}
+
+// serve returns the response to the module request req, which has the
+// specifier being required as its body and the path of the requiring module.
+func (l *moduleLoader) serve(req jsonMsg) jsonMsg {
+	path, src, err := l.load(req.Path, req.Body)
+	if err != nil {
+		return jsonMsg{Name: "module-response", Error: &jsonError{Name: "Error", Message: err.Error()}}
+	}
+	return jsonMsg{Name: "module-response", Body: string(src), Path: path, ModuleType: jsModuleType(path)}
+}
+
+func (l *moduleLoader) load(from string, spec string) (string, []byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded[from] {
+		return "", nil, fmt.Errorf("Cannot find module '%s' from '%s'", spec, from) //nolint:staticcheck // Shown to the user
+	}
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		return "", nil, fmt.Errorf("Cannot find module '%s' from '%s'. Only relative requires are supported in JS configs.", spec, from) //nolint:staticcheck // Shown to the user
+	}
+
+	path := resolveJSModule(filepath.Join(filepath.Dir(from), filepath.FromSlash(spec)))
+	if path == "" {
+		return "", nil, fmt.Errorf("Cannot find module '%s' from '%s'", spec, from) //nolint:staticcheck // Shown to the user
+	}
+	src, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return "", nil, fmt.Errorf("runner: reading module: %w", err)
+	}
+	l.loaded[path] = true
+	return path, src, nil
+}
+
+// resolveJSModule returns the file for the module at path, trying
+// jsModuleExtensions and index files of a directory, or an empty string if
+// there is none.
+func resolveJSModule(path string) string {
+	if isFile(path) {
+		return path
+	}
+	for _, ext := range jsModuleExtensions {
+		if isFile(path + ext) {
+			return path + ext
+		}
+	}
+	for _, ext := range jsModuleExtensions {
+		if p := filepath.Join(path, "index"+ext); isFile(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// jsModuleType returns whether the JS module at path is an ES module or
+// CommonJS, "module" or "commonjs", like Node: by its extension, or for .js files
+// the type in the nearest package.json.
+func jsModuleType(path string) string {
+	switch filepath.Ext(path) {
+	case ".mjs":
+		return "module"
+	case ".cjs":
+		return "commonjs"
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if b, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil { //nolint:gosec
+			var pkg struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(b, &pkg) == nil && pkg.Type == "module" {
+				return "module"
+			}
+			return "commonjs"
+		}
+		if filepath.Dir(dir) == dir {
+			return "commonjs"
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/wasm"
+)
+
+func TestModuleLoader(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"prettier.config.js":        `require("./base")`,
+		"base.cjs":                  `module.exports = {}`,
+		"lib/index.js":              `module.exports = {}`,
+		"lib/options.json":          `{}`,
+		"node_modules/pkg/index.js": `module.exports = {}`,
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	cfg := filepath.Join(dir, "prettier.config.js")
+	l := &moduleLoader{loaded: map[string]bool{cfg: true}}
+
+	tests := []struct {
+		name string
+		from string
+		spec string
+		exp  string
+		err  string
+	}{
+		{
+			name: "extension",
+			from: cfg,
+			spec: "./base",
+			exp:  "base.cjs",
+		},
+		{
+			name: "directory index",
+			from: cfg,
+			spec: "./lib",
+			exp:  "lib/index.js",
+		},
+		{
+			name: "relative to loaded module",
+			from: filepath.Join(dir, "lib", "index.js"),
+			spec: "./options.json",
+			exp:  "lib/options.json",
+		},
+		{
+			name: "parent of loaded module",
+			from: filepath.Join(dir, "lib", "index.js"),
+			spec: "../base.cjs",
+			exp:  "base.cjs",
+		},
+		{
+			name: "package",
+			from: cfg,
+			spec: "pkg",
+			err:  "Only relative requires are supported",
+		},
+		{
+			name: "builtin",
+			from: cfg,
+			spec: "node:fs",
+			err:  "Only relative requires are supported",
+		},
+		{
+			name: "missing",
+			from: cfg,
+			spec: "./missing",
+			err:  "Cannot find module './missing'",
+		},
+		{
+			name: "from module not loaded",
+			from: filepath.Join(dir, "node_modules", "pkg", "index.js"),
+			spec: "./index.js",
+			err:  "Cannot find module './index.js'",
+		},
+	}
+
+	// Cases run in order since loading a module allows requires relative to it.
+	for _, tc := range tests {
+		res := l.serve(jsonMsg{Name: "module-request", Body: tc.spec, Path: tc.from})
+		require.Equal(t, "module-response", res.Name, tc.name)
+		if tc.err != "" {
+			require.NotNil(t, res.Error, tc.name)
+			require.Contains(t, res.Error.Message, tc.err, tc.name)
+			continue
+		}
+		require.Nil(t, res.Error, tc.name)
+		exp := filepath.Join(dir, filepath.FromSlash(tc.exp))
+		require.Equal(t, exp, res.Path, tc.name)
+		b, err := os.ReadFile(exp)
+		require.NoError(t, err)
+		require.Equal(t, string(b), res.Body, tc.name)
+	}
+}
+
+func TestJSModuleType(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"package.json":     `{"type": "module"}`,
+		"cjs/package.json": `{"name": "cjs"}`,
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	require.Equal(t, "module", jsModuleType(filepath.Join(dir, "prettier.config.js")))
+	require.Equal(t, "module", jsModuleType(filepath.Join(dir, "sub", "base.js")))
+	require.Equal(t, "commonjs", jsModuleType(filepath.Join(dir, "config.cjs")))
+	// The nearest package.json applies even without a type.
+	require.Equal(t, "commonjs", jsModuleType(filepath.Join(dir, "cjs", "prettier.config.js")))
+	require.Equal(t, "module", jsModuleType(filepath.Join(dir, "cjs", "prettier.config.mjs")))
+}
+
+func TestEvalJSConfig(t *testing.T) {
+	if len(wasm.Prettier) == 0 {
+		t.Skip("prettier.wasm is not built")
+	}
+	r := NewRunner()
+	defer func() { _ = r.Close(t.Context()) }()
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		config string
+		exp    any
+	}{
+		{
+			name: "export as default",
+			files: map[string]string{
+				"prettier.config.mjs": "const config = { semi: false };\nexport { config as default };\n",
+			},
+			config: "prettier.config.mjs",
+			exp:    map[string]any{"semi": false},
+		},
+		{
+			name: "default and named import",
+			files: map[string]string{
+				"base.mjs":            "export const tabWidth = 4;\nexport default { semi: false };\n",
+				"prettier.config.mjs": "import base, { tabWidth as tw } from \"./base.mjs\";\nexport default { ...base, tabWidth: tw };\n",
+			},
+			config: "prettier.config.mjs",
+			exp:    map[string]any{"semi": false, "tabWidth": float64(4)},
+		},
+		{
+			name: "commonjs mentioning export",
+			files: map[string]string{
+				"prettier.config.js": "// export default is only for ES modules.\nconst exportName = \"export\";\nmodule.exports = {\n  semi: false,\n  [exportName]: true,\n};\n",
+			},
+			config: "prettier.config.js",
+			exp:    map[string]any{"semi": false, "export": true},
+		},
+		{
+			name: "type module",
+			files: map[string]string{
+				"package.json":       `{"type": "module"}`,
+				"prettier.config.js": "export default { semi: false };\n",
+			},
+			config: "prettier.config.js",
+			exp:    map[string]any{"semi": false},
+		},
+		{
+			name: "statements in template literals and comments",
+			files: map[string]string{
+				"prettier.config.mjs": "const banner = `\nexport default 1\n${`\nimport a from \"./a\"\n`}`;\n/*\nexport default 2\n*/\nexport default { banner };\n",
+			},
+			config: "prettier.config.mjs",
+			exp:    map[string]any{"banner": "\nexport default 1\n\nimport a from \"./a\"\n"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+
+			res, err := r.evalJSConfig(t.Context(), filepath.Join(dir, tc.config))
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, res)
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// https://spec.json5.org/
+
+var errJSON5EOF = errors.New("unexpected end of input")
+
+// parseJSON5 parses JSON5, which is also used to parse JSONC since JSONC is a subset.
+// Values are decoded to the same types as encoding/json when unmarshaling into any.
+func parseJSON5(b []byte) (any, error) {
+	p := json5Parser{src: string(b)}
+	p.skipSpace()
+	if p.err != nil {
+		return nil, p.err
+	}
+	v := p.parseValue()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.skipSpace()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected character %q", p.src[p.pos])
+	}
+	return v, nil
+}
+
+type json5Parser struct {
+	src string
+	pos int
+	err error
+}
+
+func (p *json5Parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.src[:p.pos], "\n")
+	col := p.pos - strings.LastIndexByte(p.src[:p.pos], '\n')
+	return fmt.Errorf("json5: %s at %d:%d", fmt.Sprintf(format, args...), line, col)
+}
+
+func (p *json5Parser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *json5Parser) skipSpace() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		switch {
+		case r == '\uFEFF' || unicode.IsSpace(r):
+			p.pos += size
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			end := strings.IndexAny(p.src[p.pos:], "\n\r\u2028\u2029")
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				p.pos = len(p.src)
+				p.fail(p.errorf("unterminated comment"))
+				return
+			}
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (p *json5Parser) parseValue() any {
+	if p.pos >= len(p.src) {
+		p.fail(errJSON5EOF)
+		return nil
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	}
+
+	start := p.pos
+	ident := p.parseIdentifier()
+	switch ident {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	case "Infinity", "NaN":
+		p.pos = start
+		p.fail(p.errorf("non-finite number %q is not supported", ident))
+		return nil
+	case "":
+		p.fail(p.errorf("unexpected character %q", p.src[p.pos]))
+		return nil
+	}
+	if p.pos >= len(p.src) {
+		p.fail(fmt.Errorf("json5: %w after %q", errJSON5EOF, ident))
+		return nil
+	}
+	p.pos = start
+	p.fail(p.errorf("unexpected identifier %q", ident))
+	return nil
+}
+
+func (p *json5Parser) parseObject() any {
+	res := map[string]any{}
+	p.pos++ // {
+	for {
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos >= len(p.src) {
+			p.fail(errJSON5EOF)
+			return nil
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			return res
+		}
+
+		var key string
+		if c := p.src[p.pos]; c == '"' || c == '\'' {
+			key = p.parseString()
+		} else {
+			key = p.parseIdentifier()
+			if key == "" {
+				p.fail(p.errorf("invalid object key"))
+			}
+		}
+		if p.err != nil {
+			return nil
+		}
+
+		p.skipSpace()
+		if !p.consume(':') {
+			return nil
+		}
+		p.skipSpace()
+		res[key] = p.parseValue()
+		if p.err != nil {
+			return nil
+		}
+
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if !p.consume('}') {
+			return nil
+		}
+		return res
+	}
+}
+
+func (p *json5Parser) parseArray() any {
+	res := []any{}
+	p.pos++ // [
+	for {
+		p.skipSpace()
+		if p.err != nil {
+			return nil
+		}
+		if p.pos >= len(p.src) {
+			p.fail(errJSON5EOF)
+			return nil
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return res
+		}
+
+		res = append(res, p.parseValue())
+		if p.err != nil {
+			return nil
+		}
+
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if !p.consume(']') {
+			return nil
+		}
+		return res
+	}
+}
+
+func (p *json5Parser) consume(c byte) bool {
+	if p.pos >= len(p.src) {
+		p.fail(errJSON5EOF)
+		return false
+	}
+	if p.src[p.pos] != c {
+		p.fail(p.errorf("expected %q but found %q", c, p.src[p.pos]))
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *json5Parser) parseIdentifier() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r == '$' || r == '_' || unicode.IsLetter(r) || (p.pos > start && unicode.IsDigit(r)) {
+			p.pos += size
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *json5Parser) parseString() string {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			p.fail(errJSON5EOF)
+			return ""
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String()
+		case c == '\n' || c == '\r':
+			p.fail(p.errorf("unterminated string"))
+			return ""
+		case c != '\\':
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		// Escape sequence
+		p.pos++
+		if p.pos >= len(p.src) {
+			p.fail(errJSON5EOF)
+			return ""
+		}
+		c = p.src[p.pos]
+		p.pos++
+		switch c {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case '0':
+			sb.WriteByte(0)
+		case '\r':
+			// Line continuation
+			if p.pos < len(p.src) && p.src[p.pos] == '\n' {
+				p.pos++
+			}
+		case '\n':
+			// Line continuation
+		case 'x':
+			sb.WriteRune(p.parseHexEscape(2))
+		case 'u':
+			r := p.parseHexEscape(4)
+			if utf8.ValidRune(r) {
+				sb.WriteRune(r)
+				break
+			}
+			// Surrogate pair
+			if strings.HasPrefix(p.src[p.pos:], "\\u") {
+				p.pos += 2
+				lo := p.parseHexEscape(4)
+				r = (r-0xD800)<<10 + (lo - 0xDC00) + 0x10000
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (p *json5Parser) parseHexEscape(n int) rune {
+	if p.pos+n > len(p.src) {
+		p.fail(errJSON5EOF)
+		return 0
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		p.fail(p.errorf("invalid escape sequence"))
+		return 0
+	}
+	p.pos += n
+	return rune(v)
+}
+
+func (p *json5Parser) parseNumber() any {
+	start := p.pos
+	neg := false
+	if c := p.src[p.pos]; c == '+' || c == '-' {
+		neg = c == '-'
+		p.pos++
+	}
+
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "Infinity"), strings.HasPrefix(rest, "NaN"):
+		// Config is passed to prettier as JSON, which can't represent
+		// non-finite numbers.
+		p.parseIdentifier()
+		num := p.src[start:p.pos]
+		p.pos = start
+		p.fail(p.errorf("non-finite number %q is not supported", num))
+		return nil
+	case strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X"):
+		p.pos += 2
+		digits := p.pos
+		for p.pos < len(p.src) && isHexDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		v, err := strconv.ParseUint(p.src[digits:p.pos], 16, 64)
+		if err != nil {
+			p.fail(p.errorf("invalid number %q", p.src[start:p.pos]))
+			return nil
+		}
+		if neg {
+			return -float64(v)
+		}
+		return float64(v)
+	}
+
+	for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		p.fail(p.errorf("invalid number %q", p.src[start:p.pos]))
+		return nil
+	}
+	return v
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
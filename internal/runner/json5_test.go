@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSON5(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		exp  any
+	}{
+		{
+			name: "json",
+			in:   `{"tabWidth": 4, "semi": false, "plugins": [], "parser": null}`,
+			exp: map[string]any{
+				"tabWidth": 4.0,
+				"semi":     false,
+				"plugins":  []any{},
+				"parser":   nil,
+			},
+		},
+		{
+			name: "json5",
+			in: `
+			// Prettier config
+			{
+				tabWidth: 4,
+				/* Strings */
+				'singleQuote': true,
+				endOfLine: 'lf',
+				overrides: [
+					{files: ["*.md",], options: {proseWrap: "always"},},
+				],
+			}
+			`,
+			exp: map[string]any{
+				"tabWidth":    4.0,
+				"singleQuote": true,
+				"endOfLine":   "lf",
+				"overrides": []any{
+					map[string]any{
+						"files":   []any{"*.md"},
+						"options": map[string]any{"proseWrap": "always"},
+					},
+				},
+			},
+		},
+		{
+			name: "numbers",
+			in:   `[0x10, -0xA, .5, 5., +1, 1e2]`,
+			exp:  []any{16.0, -10.0, 0.5, 5.0, 1.0, 100.0},
+		},
+		{
+			name: "strings",
+			in: `['a\'b', "c\"d", "\x41é\n", "line \
+continued", "😀"]`,
+			exp: []any{"a'b", `c"d`, "Aé\n", "line continued", "😀"},
+		},
+		{
+			name: "string",
+			in:   `"@acme/prettier-config"`,
+			exp:  "@acme/prettier-config",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseJSON5([]byte(tc.in))
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, res)
+		})
+	}
+}
+
+func TestParseJSON5Invalid(t *testing.T) {
+	for _, in := range []string{
+		`{`,
+		`{a 1}`,
+		`{"a": 1} 2`,
+		`[1 2]`,
+		`"unterminated`,
+		`/* unterminated`,
+		`{a: undefined}`,
+		`Infinity`,
+		`{a: -Infinity}`,
+		`[NaN]`,
+		`1e999`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := parseJSON5([]byte(in))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseJSON5Truncated(t *testing.T) {
+	for in, msg := range map[string]string{
+		`tru`:       `json5: unexpected end of input after "tru"`,
+		`{a: fals`:  `json5: unexpected end of input after "fals"`,
+		`{a: nope}`: `json5: unexpected identifier "nope" at 1:5`,
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := parseJSON5([]byte(in))
+			require.EqualError(t, err, msg)
+		})
+	}
+	_, err := parseJSON5([]byte(`tru`))
+	require.ErrorIs(t, err, errJSON5EOF)
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Prettier, being JavaScript, indexes strings by UTF-16 code units while Go
+// indexes by bytes.
+
+// UTF16Offset converts the byte offset off in s to an offset in UTF-16 code
+// units. Offsets past the end of s are clamped to its length.
+func UTF16Offset(s []byte, off int) int {
+	off = min(max(off, 0), len(s))
+	n := 0
+	for i := 0; i < off; {
+		r, size := utf8.DecodeRune(s[i:])
+		i += size
+		n += utf16Len(r)
+	}
+	return n
+}
+
+// ByteOffset converts the offset off in UTF-16 code units in s to a byte
+// offset. Offsets past the end of s are clamped to its length, and offsets
+// within a character are moved to its start.
+func ByteOffset(s []byte, off int) int {
+	n := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRune(s[i:])
+		n += utf16Len(r)
+		if n > off {
+			return i
+		}
+		i += size
+	}
+	return len(s)
+}
+
+func utf16Len(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	// Invalid UTF-8 is decoded to the replacement character by JavaScript.
+	return 1
+}
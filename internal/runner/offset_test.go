@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsets(t *testing.T) {
+	// é is 2 bytes and 1 UTF-16 unit, 😀 is 4 bytes and 2 UTF-16 units.
+	s := []byte("aé😀b")
+
+	tests := []struct {
+		byteOff  int
+		utf16Off int
+	}{
+		{byteOff: 0, utf16Off: 0},
+		{byteOff: 1, utf16Off: 1},
+		{byteOff: 3, utf16Off: 2},
+		{byteOff: 7, utf16Off: 4},
+		{byteOff: 8, utf16Off: 5},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.utf16Off, UTF16Offset(s, tc.byteOff), "byte offset %d", tc.byteOff)
+		require.Equal(t, tc.byteOff, ByteOffset(s, tc.utf16Off), "UTF-16 offset %d", tc.utf16Off)
+	}
+
+	// Out of range offsets are clamped.
+	require.Equal(t, 5, UTF16Offset(s, 100))
+	require.Equal(t, 0, UTF16Offset(s, -1))
+	require.Equal(t, 8, ByteOffset(s, 100))
+	// An offset between the surrogates of 😀 moves to its start.
+	require.Equal(t, 3, ByteOffset(s, 3))
+}
@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	gofmt "go/format"
+	"io"
+	"os"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// pool keeps warm prettier instances so that QuickJS startup and evaluation of
+// the prettier bundle only need to happen once per instance rather than once per
+// file. Each instance runs the guest in a loop, handling one request at a time
+// over the jsonMsg protocol until its stdin is closed.
+type pool struct {
+	rt       wazero.Runtime
+	compiled wazero.CompiledModule
+
+	// slots bounds the number of live instances.
+	slots chan struct{}
+	idle  chan *instance
+}
+
+func newPool(rt wazero.Runtime, compiled wazero.CompiledModule, size int) *pool {
+	return &pool{
+		rt:       rt,
+		compiled: compiled,
+		slots:    make(chan struct{}, size),
+		idle:     make(chan *instance, size),
+	}
+}
+
+type instance struct {
+	stdin  *io.PipeWriter
+	stdout *io.PipeReader
+	enc    *json.Encoder
+	out    *bufio.Reader
+	done   chan struct{}
+}
+
+// request sends msg to an instance from the pool and returns the guest's reply.
+func (p *pool) request(ctx context.Context, msg jsonMsg) (jsonMsg, error) {
+	return p.do(ctx, msg, nil, true)
+}
+
+// requestIsolated is like request but serves modules requested by the guest from
+// modules and does not reuse the instance afterwards, since msg runs user code
+// that may change the guest's global state.
+func (p *pool) requestIsolated(ctx context.Context, msg jsonMsg, modules *moduleLoader) (jsonMsg, error) {
+	return p.do(ctx, msg, modules, false)
+}
+
+func (p *pool) do(ctx context.Context, msg jsonMsg, modules *moduleLoader, reuse bool) (jsonMsg, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return jsonMsg{}, fmt.Errorf("runner: waiting for prettier instance: %w", ctx.Err())
+	}
+
+	var inst *instance
+	select {
+	case inst = <-p.idle:
+	default:
+		inst = p.start(ctx)
+	}
+
+	// Closing the pipes when ctx is done unblocks the request.
+	stop := context.AfterFunc(ctx, inst.closePipes)
+	res, err := inst.request(msg, modules)
+	if !stop() {
+		// The guest exits when it next reads or writes, but it may still be busy
+		// formatting, so only release its slot once it has.
+		go func() {
+			<-inst.done
+			<-p.slots
+		}()
+		if err != nil {
+			err = fmt.Errorf("runner: request to prettier canceled: %w", ctx.Err())
+		}
+		return res, err
+	}
+
+	if err != nil || !reuse {
+		// The guest may be in an inconsistent state so don't reuse it.
+		inst.close()
+	} else {
+		p.idle <- inst
+	}
+	<-p.slots
+
+	return res, err
+}
+
+func (p *pool) start(ctx context.Context) *instance {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	inst := &instance{
+		stdin:  stdinW,
+		stdout: stdoutR,
+		enc:    json.NewEncoder(stdinW),
+		out:    bufio.NewReader(stdoutR),
+		done:   make(chan struct{}),
+	}
+
+	mCfg := wazero.NewModuleConfig().
+		WithName("").
+		WithSysNanosleep().
+		WithSysNanotime().
+		WithSysWalltime().
+		WithRandSource(rand.Reader).
+		WithArgs("prettier").
+		WithStdin(stdinR).
+		WithStderr(stdoutW). // Use stderr for communication to avoid buffering challenges
+		WithStdout(os.Stderr)
+
+	// The instance outlives the request that started it.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer close(inst.done)
+		mod, err := p.rt.InstantiateModule(ctx, p.compiled, mCfg)
+		if mod != nil {
+			_ = mod.Close(ctx)
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		// Unblock any pending read with the reason the guest exited.
+		_ = stdoutW.CloseWithError(err)
+		_ = stdinR.Close()
+	}()
+
+	return inst
+}
+
+func (i *instance) request(msg jsonMsg, modules *moduleLoader) (jsonMsg, error) {
+	if err := i.enc.Encode(msg); err != nil {
+		return jsonMsg{}, fmt.Errorf("runner: encoding %s message for prettier: %w", msg.Name, err)
+	}
+
+	for {
+		line, err := i.out.ReadString('\n')
+		if err != nil {
+			return jsonMsg{}, fmt.Errorf("runner: reading message from prettier: %w", err)
+		}
+		var res jsonMsg
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			return jsonMsg{}, fmt.Errorf("runner: unmarshaling message from prettier: %w", err)
+		}
+		switch res.Name {
+		case "gofmt-request":
+			// TODO: Consider if err needs to be handled.
+			formatted, err := gofmt.Source([]byte(res.Body))
+			if err != nil {
+				// This should only apply to an embedded string, treat it as best-effort.
+				formatted = []byte(res.Body)
+			}
+			msg := jsonMsg{
+				Name: "gofmt-response",
+				Body: string(formatted),
+			}
+			if err := i.enc.Encode(msg); err != nil {
+				return jsonMsg{}, fmt.Errorf("runner: encoding gofmt response message for prettier: %w", err)
+			}
+		case "module-request":
+			if modules == nil {
+				return jsonMsg{}, fmt.Errorf("runner: unexpected module request from prettier for %s", res.Body)
+			}
+			if err := i.enc.Encode(modules.serve(res)); err != nil {
+				return jsonMsg{}, fmt.Errorf("runner: encoding module response message for prettier: %w", err)
+			}
+		default:
+			return res, nil
+		}
+	}
+}
+
+// close signals the guest to exit by closing its stdio and waits for it.
+func (i *instance) close() {
+	i.closePipes()
+	<-i.done
+}
+
+func (i *instance) closePipes() {
+	_ = i.stdin.Close()
+	_ = i.stdout.Close()
+}
+
+// close shuts down all idle instances.
+func (p *pool) close() {
+	for {
+		select {
+		case inst := <-p.idle:
+			inst.close()
+		default:
+			return
+		}
+	}
+}
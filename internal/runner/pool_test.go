@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRequestCanceled(t *testing.T) {
+	p := newPool(nil, nil, 1)
+	defer p.close()
+
+	// The guest never replies, exiting only once its stdin is closed.
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	inst := &instance{
+		stdin:  stdinW,
+		stdout: stdoutR,
+		enc:    json.NewEncoder(stdinW),
+		out:    bufio.NewReader(stdoutR),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(inst.done)
+		_, _ = io.Copy(io.Discard, stdinR)
+		_ = stdoutW.CloseWithError(io.EOF)
+	}()
+	p.idle <- inst
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.request(ctx, jsonMsg{Name: "format"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The instance is discarded and its slot released once it has exited.
+	<-inst.done
+	require.Eventually(t, func() bool { return len(p.slots) == 0 }, time.Second, time.Millisecond)
+	require.Empty(t, p.idle)
+}
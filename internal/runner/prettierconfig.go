@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"fmt"
 	"maps"
 	"path/filepath"
 	"strings"
@@ -8,36 +9,72 @@ import (
 	"github.com/bmatcuk/doublestar/v4"
 )
 
-func mergePrettierConfig(mergedCfg map[string]any, userCfg map[string]any, path string) {
-	var overrides []map[string]any
+// https://github.com/prettier/prettier/blob/main/src/config/resolve-config.js
+
+// mergePrettierConfig merges userCfg, loaded from the config file at cfgPath,
+// into mergedCfg for formatting the file at path. Like upstream, override
+// patterns are matched against the path relative to the config file's directory
+// and every matching override is applied in order.
+func mergePrettierConfig(mergedCfg map[string]any, userCfg map[string]any, cfgPath string, path string) {
 	for k, v := range userCfg {
-		if k == "overrides" {
-			if o, ok := v.([]map[string]any); ok {
-				overrides = o
-				continue
-			}
+		if k != "overrides" {
+			mergedCfg[k] = v
 		}
-		mergedCfg[k] = v
 	}
 
+	overrides := toMaps(userCfg["overrides"])
 	if len(overrides) == 0 {
 		return
 	}
 
-	for _, o := range overrides {
-		patterns := toStrings(o["files"])
-		ignores := toStrings(o["excludeFiles"])
+	rel := path
+	if cfgPath != "" {
+		if r, err := filepath.Rel(filepath.Dir(cfgPath), path); err == nil {
+			rel = r
+		}
+	}
+	rel = filepath.ToSlash(rel)
 
-		if matchAny(ignores, path) {
+	for _, o := range overrides {
+		if !pathMatchesGlobs(rel, toStrings(o["files"]), toStrings(o["excludeFiles"])) {
 			continue
 		}
+		if opts := toMap(o["options"]); opts != nil {
+			maps.Copy(mergedCfg, opts)
+		}
+	}
+}
 
-		if matchAny(patterns, path) {
-			if m, ok := o["options"].(map[string]any); ok {
-				maps.Copy(mergedCfg, m)
+// toMaps returns v as a slice of maps, accepting the shapes produced by the
+// JSON, YAML and TOML decoders.
+func toMaps(v any) []map[string]any {
+	switch v := v.(type) {
+	case []map[string]any:
+		return v
+	case []any:
+		res := make([]map[string]any, 0, len(v))
+		for _, e := range v {
+			if m := toMap(e); m != nil {
+				res = append(res, m)
 			}
 		}
+		return res
+	}
+	return nil
+}
+
+func toMap(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		for k, e := range v {
+			res[fmt.Sprint(k)] = e
+		}
+		return res
 	}
+	return nil
 }
 
 func toStrings(v any) []string {
@@ -46,19 +83,49 @@ func toStrings(v any) []string {
 		return []string{v}
 	case []string:
 		return v
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
 	}
 	return nil
 }
 
-func matchAny(patterns []string, path string) bool {
+// pathMatchesGlobs returns whether path matches any of patterns and none of
+// excludes. Like upstream, patterns without a slash are matched against the
+// basename and excludes are matched the same way as the pattern they exclude
+// from.
+func pathMatchesGlobs(path string, patterns []string, excludes []string) bool {
+	var withSlashes, withoutSlashes []string
+	for _, p := range patterns {
+		if strings.Contains(p, "/") {
+			withSlashes = append(withSlashes, p)
+		} else {
+			withoutSlashes = append(withoutSlashes, p)
+		}
+	}
+
+	return matchGlobs(path, withoutSlashes, excludes, true) || matchGlobs(path, withSlashes, excludes, false)
+}
+
+func matchGlobs(path string, patterns []string, excludes []string, basename bool) bool {
+	return len(patterns) > 0 && matchAny(patterns, path, basename) && !matchAny(excludes, path, basename)
+}
+
+// matchAny returns whether path matches any of patterns. With basename, patterns
+// without a slash are matched against the basename of path.
+func matchAny(patterns []string, path string, basename bool) bool {
 	for _, p := range patterns {
 		target := path
-		// Pattern without slash is matched against basename, not full path
-		if strings.IndexByte(p, '/') == -1 {
+		if basename && !strings.Contains(p, "/") {
 			target = filepath.Base(path)
 		}
-
-		if m, _ := doublestar.Match(p, target); m {
+		// Like micromatch, a leading ./ is not significant.
+		if m, _ := doublestar.Match(strings.TrimPrefix(p, "./"), strings.TrimPrefix(target, "./")); m {
 			return true
 		}
 	}
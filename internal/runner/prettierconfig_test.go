@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -40,10 +41,11 @@ func TestMergePrettierConfig(t *testing.T) {
 	}
 
 	tests := []struct {
-		name string
-		in   map[string]any
-		exp  map[string]any
-		path string
+		name    string
+		in      map[string]any
+		cfgPath string
+		exp     map[string]any
+		path    string
 	}{
 		{
 			name: "no overrides",
@@ -106,29 +108,113 @@ func TestMergePrettierConfig(t *testing.T) {
 			path: "animals/testdata/bear.js",
 		},
 		{
-			name: "glob match exclude",
+			// Like upstream, excludes are matched against the full path for patterns
+			// with a slash.
+			name: "glob match exclude basename",
 			in:   overridesCfg,
 			exp: map[string]any{
-				"tabWidth":   4,
+				"tabWidth":   6,
 				"printWidth": 100,
 			},
 			path: "animals/testdata/cat.js",
 		},
 		{
-			name: "glob match exclude 2",
+			name: "glob match exclude basename 2",
 			in:   overridesCfg,
 			exp: map[string]any{
-				"tabWidth":   4,
+				"tabWidth":   6,
 				"printWidth": 99,
 			},
 			path: "animals/testdata/mouse.js",
 		},
+		{
+			name: "glob match exclude",
+			in: map[string]any{
+				"overrides": []any{
+					map[string]any{
+						"files":        "src/**/*.js",
+						"excludeFiles": "src/vendor/**",
+						"options":      map[string]any{"tabWidth": 4},
+					},
+				},
+			},
+			exp:  map[string]any{},
+			path: "src/vendor/a.js",
+		},
+		{
+			name: "decoded from YAML or JSON",
+			in: map[string]any{
+				"overrides": []any{
+					map[string]any{
+						"files":   []any{"docs/**/*.md", "*.mdx"},
+						"options": map[string]any{"proseWrap": "always"},
+					},
+					map[string]any{
+						"files":        "*.md",
+						"excludeFiles": []any{"CHANGELOG.md"},
+						"options":      map[string]any{"tabWidth": 4},
+					},
+				},
+			},
+			exp: map[string]any{
+				"proseWrap": "always",
+				"tabWidth":  4,
+			},
+			path: "docs/guide/intro.md",
+		},
+		{
+			name: "decoded from YAML or JSON no match",
+			in: map[string]any{
+				"overrides": []any{
+					map[string]any{
+						"files":   []any{"docs/**/*.md"},
+						"options": map[string]any{"proseWrap": "always"},
+					},
+				},
+			},
+			exp:  map[string]any{},
+			path: "README.md",
+		},
+		{
+			name: "decoded from TOML",
+			in: map[string]any{
+				"semi": false,
+				"overrides": []map[string]any{
+					{
+						"files":   []any{"*.ts"},
+						"options": map[string]any{"semi": true},
+					},
+					{
+						"files":   []any{"legacy/**"},
+						"options": map[string]any{"semi": false},
+					},
+				},
+			},
+			exp: map[string]any{
+				"semi": false,
+			},
+			path: "legacy/a.ts",
+		},
+		{
+			name:    "relative to config file",
+			in:      map[string]any{"overrides": []any{map[string]any{"files": "docs/*.md", "options": map[string]any{"proseWrap": "always"}}}},
+			cfgPath: filepath.Join("project", ".prettierrc"),
+			exp:     map[string]any{"proseWrap": "always"},
+			path:    filepath.Join("project", "docs", "a.md"),
+		},
+		{
+			name:    "relative to config file no match",
+			in:      map[string]any{"overrides": []any{map[string]any{"files": "docs/*.md", "options": map[string]any{"proseWrap": "always"}}}},
+			cfgPath: filepath.Join("project", ".prettierrc"),
+			exp:     map[string]any{},
+			path:    filepath.Join("project", "sub", "docs", "a.md"),
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			res := map[string]any{}
-			mergePrettierConfig(res, tc.in, tc.path)
+			mergePrettierConfig(res, tc.in, tc.cfgPath, tc.path)
 			require.Equal(t, tc.exp, res)
 		})
 	}
@@ -0,0 +1,279 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Values for RunArgs.Reporter.
+const (
+	ReporterJSON   = "json"
+	ReporterSARIF  = "sarif"
+	ReporterGitHub = "github"
+)
+
+// Values for fileResult.Status.
+const (
+	fileStatusFormatted   = "formatted"
+	fileStatusUnchanged   = "unchanged"
+	fileStatusUnsupported = "unsupported"
+	fileStatusError       = "error"
+)
+
+// fileResult is the outcome of formatting a single file, collected for reporters.
+// A formatted status means the file's content differed from prettier's output,
+// whether or not it was written. Errors expanding patterns, such as a pattern
+// matching no files, are reported as errors without a path.
+type fileResult struct {
+	Path    string    `json:"path,omitempty"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Loc     *Location `json:"loc,omitempty"`
+
+	syntaxError bool
+}
+
+func newErrorResult(path string, err error) fileResult {
+	res := fileResult{Path: path, Status: fileStatusError, Message: err.Error()}
+	var se *SyntaxError
+	if errors.As(err, &se) {
+		res.Message = se.Message
+		res.Loc = se.Loc
+		res.syntaxError = true
+	}
+	return res
+}
+
+// writeReport writes results to w in the format of reporter. Paths are reported
+// relative to cwd with forward slashes so they match repository paths in CI.
+func writeReport(w io.Writer, reporter string, results []fileResult, args RunArgs) error {
+	results = normalizeResults(results, args.Cwd)
+
+	switch reporter {
+	case ReporterJSON:
+		return writeJSON(w, jsonReport{Files: results})
+	case ReporterSARIF:
+		return writeJSON(w, newSARIFReport(results, args.Write))
+	case ReporterGitHub:
+		return writeGitHubReport(w, results, args.Write)
+	}
+	return fmt.Errorf("runner: unknown reporter %q", reporter)
+}
+
+func normalizeResults(results []fileResult, cwd string) []fileResult {
+	absCwd, err := filepath.Abs(cwd)
+	if err != nil {
+		absCwd = cwd
+	}
+	res := make([]fileResult, len(results))
+	for i, r := range results {
+		if filepath.IsAbs(r.Path) {
+			if rel, err := filepath.Rel(absCwd, r.Path); err == nil && !strings.HasPrefix(rel, "..") {
+				r.Path = rel
+			}
+		}
+		r.Path = filepath.ToSlash(r.Path)
+		res[i] = r
+	}
+	// Files are formatted concurrently so sort for stable output.
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("runner: writing report: %w", err)
+	}
+	return nil
+}
+
+type jsonReport struct {
+	Files []fileResult `json:"files"`
+}
+
+const (
+	ruleUnformatted = "unformatted"
+	ruleSyntaxError = "syntax-error"
+	ruleError       = "error"
+)
+
+const unformattedMessage = "File is not formatted with Prettier. Run Prettier to fix."
+
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+// https://docs.github.com/en/code-security/code-scanning/integrating-with-code-scanning/sarif-support-for-code-scanning
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+func newSARIFReport(results []fileResult, write bool) sarifReport {
+	sarifResults := []sarifResult{}
+	for _, r := range results {
+		var res sarifResult
+		switch {
+		case r.Status == fileStatusFormatted && !write:
+			res = sarifResult{
+				RuleID:  ruleUnformatted,
+				Level:   "warning",
+				Message: sarifMessage{Text: unformattedMessage},
+			}
+		case r.Status == fileStatusError && r.syntaxError:
+			res = sarifResult{
+				RuleID:  ruleSyntaxError,
+				Level:   "error",
+				Message: sarifMessage{Text: r.Message},
+			}
+		case r.Status == fileStatusError:
+			res = sarifResult{
+				RuleID:  ruleError,
+				Level:   "error",
+				Message: sarifMessage{Text: r.Message},
+			}
+		default:
+			continue
+		}
+		if r.Path == "" {
+			sarifResults = append(sarifResults, res)
+			continue
+		}
+		// Code scanning requires a region, so point at the start of the file when
+		// there is no more specific location.
+		region := sarifRegion{StartLine: 1, StartColumn: 1}
+		if r.Loc != nil {
+			region = sarifRegion{StartLine: r.Loc.Start.Line, StartColumn: r.Loc.Start.Column}
+			if r.Loc.End != nil {
+				region.EndLine = r.Loc.End.Line
+				region.EndColumn = r.Loc.End.Column
+			}
+		}
+		res.Locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: r.Path, URIBaseID: "%SRCROOT%"},
+				Region:           region,
+			},
+		}}
+		sarifResults = append(sarifResults, res)
+	}
+
+	return sarifReport{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "prettier",
+				InformationURI: "https://prettier.io",
+				Rules: []sarifRule{
+					{ID: ruleUnformatted, ShortDescription: sarifMessage{Text: "File is not formatted with Prettier"}},
+					{ID: ruleSyntaxError, ShortDescription: sarifMessage{Text: "File could not be parsed by Prettier"}},
+					{ID: ruleError, ShortDescription: sarifMessage{Text: "Prettier failed to process the file or pattern"}},
+				},
+			}},
+			Results: sarifResults,
+		}},
+	}
+}
+
+// https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions#setting-an-error-message
+
+func writeGitHubReport(w io.Writer, results []fileResult, write bool) error {
+	for _, r := range results {
+		var line string
+		switch {
+		case r.Status == fileStatusFormatted && !write:
+			line = fmt.Sprintf("::error file=%s,title=Prettier::%s", escapeGitHubProperty(r.Path), escapeGitHubData(unformattedMessage))
+		case r.Status == fileStatusError && r.Path == "":
+			line = "::error title=Prettier::" + escapeGitHubData(r.Message)
+		case r.Status == fileStatusError:
+			props := "file=" + escapeGitHubProperty(r.Path)
+			if r.Loc != nil {
+				props += fmt.Sprintf(",line=%d,col=%d", r.Loc.Start.Line, r.Loc.Start.Column)
+				if r.Loc.End != nil {
+					props += fmt.Sprintf(",endLine=%d,endColumn=%d", r.Loc.End.Line, r.Loc.End.Column)
+				}
+			}
+			line = fmt.Sprintf("::error %s,title=Prettier::%s", props, escapeGitHubData(r.Message))
+		default:
+			continue
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("runner: writing report: %w", err)
+		}
+	}
+	return nil
+}
+
+var (
+	gitHubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	gitHubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeGitHubData(s string) string {
+	return gitHubDataEscaper.Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return gitHubPropertyEscaper.Replace(s)
+}
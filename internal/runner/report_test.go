@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReport(t *testing.T) {
+	cwd := t.TempDir()
+
+	results := []fileResult{
+		{Path: filepath.Join(cwd, "b.md"), Status: fileStatusFormatted},
+		{Path: "a.yaml", Status: fileStatusUnchanged},
+		{Path: "c.unknown", Status: fileStatusUnsupported},
+		newErrorResult("d,e.json", &SyntaxError{
+			Path:    "d,e.json",
+			Message: "Unexpected token\nhere",
+			Loc:     &Location{Start: Position{Line: 2, Column: 3}},
+		}),
+		newErrorResult("f.md", errors.New("permission denied")),
+		{Status: fileStatusError, Message: `No files matching the pattern were found: "g/*.md".`},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeReport(&out, ReporterJSON, results, RunArgs{Cwd: cwd}))
+
+		var report jsonReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, []fileResult{
+			{Status: fileStatusError, Message: `No files matching the pattern were found: "g/*.md".`},
+			{Path: "a.yaml", Status: fileStatusUnchanged},
+			{Path: "b.md", Status: fileStatusFormatted},
+			{Path: "c.unknown", Status: fileStatusUnsupported},
+			{
+				Path:    "d,e.json",
+				Status:  fileStatusError,
+				Message: "Unexpected token\nhere",
+				Loc:     &Location{Start: Position{Line: 2, Column: 3}},
+			},
+			{Path: "f.md", Status: fileStatusError, Message: "permission denied"},
+		}, report.Files)
+	})
+
+	t.Run("github", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeReport(&out, ReporterGitHub, results, RunArgs{Cwd: cwd}))
+		require.Equal(t, "::error title=Prettier::No files matching the pattern were found: \"g/*.md\".\n"+
+			"::error file=b.md,title=Prettier::File is not formatted with Prettier. Run Prettier to fix.\n"+
+			"::error file=d%2Ce.json,line=2,col=3,title=Prettier::Unexpected token%0Ahere\n"+
+			"::error file=f.md,title=Prettier::permission denied\n", out.String())
+	})
+
+	t.Run("github write", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeReport(&out, ReporterGitHub, results, RunArgs{Cwd: cwd, Write: true}))
+		require.Equal(t, "::error title=Prettier::No files matching the pattern were found: \"g/*.md\".\n"+
+			"::error file=d%2Ce.json,line=2,col=3,title=Prettier::Unexpected token%0Ahere\n"+
+			"::error file=f.md,title=Prettier::permission denied\n", out.String())
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeReport(&out, ReporterSARIF, results, RunArgs{Cwd: cwd}))
+
+		var report sarifReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		require.Equal(t, "2.1.0", report.Version)
+		require.Len(t, report.Runs, 1)
+		run := report.Runs[0]
+		require.Equal(t, "prettier", run.Tool.Driver.Name)
+		require.Len(t, run.Results, 4)
+
+		require.Equal(t, ruleError, run.Results[0].RuleID)
+		require.Equal(t, "error", run.Results[0].Level)
+		require.Empty(t, run.Results[0].Locations)
+
+		require.Equal(t, ruleUnformatted, run.Results[1].RuleID)
+		require.Equal(t, "b.md", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		require.Equal(t, sarifRegion{StartLine: 1, StartColumn: 1}, run.Results[1].Locations[0].PhysicalLocation.Region)
+
+		require.Equal(t, ruleSyntaxError, run.Results[2].RuleID)
+		require.Equal(t, "error", run.Results[2].Level)
+		require.Equal(t, "d,e.json", run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		require.Equal(t, sarifRegion{StartLine: 2, StartColumn: 3}, run.Results[2].Locations[0].PhysicalLocation.Region)
+
+		require.Equal(t, ruleError, run.Results[3].RuleID)
+		require.Equal(t, "f.md", run.Results[3].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	})
+}
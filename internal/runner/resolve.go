@@ -0,0 +1,252 @@
+package runner
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/editorconfig/editorconfig-core-go/v2"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/config/resolve-config.js
+// https://github.com/prettier/prettier/blob/main/src/cli/options/get-options-for-file.js
+
+// Values for RunArgs.ConfigPrecedence.
+const (
+	ConfigPrecedenceCLIOverride  = "cli-override"
+	ConfigPrecedenceFileOverride = "file-override"
+	ConfigPrecedencePreferFile   = "prefer-file"
+)
+
+// configResolver resolves the options to format each file with. Like upstream,
+// the config file closest to each file is used rather than one for the whole run,
+// and lookups are cached per directory.
+type configResolver struct {
+	noConfig         bool
+	noEditorConfig   bool
+	configPrecedence string
+	// cliOptions are the format options passed as CLI flags.
+	cliOptions map[string]any
+	// parser is forced for all files when set.
+	parser string
+
+	// explicit is the config specified with --config, if any.
+	explicit map[string]any
+	// explicitPath is the path of explicit, which override patterns are relative to.
+	explicitPath string
+
+	// runner validates config files and evaluates JS configs. Config is not
+	// validated and JS configs are not supported when it is nil.
+	runner *Runner
+
+	mu sync.Mutex
+	// ecCfg is not safe for concurrent use so must only be used with mu held.
+	ecCfg editorconfig.Config
+	// dirs maps a directory to the path of the config file closest to it.
+	dirs map[string]string
+	// configs maps a config file path to its loaded content.
+	configs map[string]*loadedConfig
+}
+
+type loadedConfig struct {
+	once sync.Once
+	cfg  map[string]any
+	err  error
+}
+
+func newConfigResolver(ctx context.Context, args RunArgs, runner *Runner) (*configResolver, error) {
+	r := &configResolver{
+		runner:           runner,
+		noConfig:         args.NoConfig,
+		noEditorConfig:   args.NoEditorConfig,
+		configPrecedence: args.ConfigPrecedence,
+		cliOptions:       args.Options,
+		parser:           args.Parser,
+		ecCfg: editorconfig.Config{
+			Parser: editorconfig.NewCachedParser(),
+		},
+		dirs:    map[string]string{},
+		configs: map[string]*loadedConfig{},
+	}
+
+	if args.Config != "" {
+		cfg, err := r.loadConfig(ctx, args.Config)
+		if err != nil {
+			return nil, err
+		}
+		r.explicit = cfg
+		r.explicitPath, _ = filepath.Abs(args.Config)
+	}
+
+	return r, nil
+}
+
+// resolve returns the options to pass to prettier for the file at filePath.
+// We use an untyped map for prettier config to allow piping through user config
+// without needing to recognizing every option.
+func (r *configResolver) resolve(ctx context.Context, filePath string) (map[string]any, error) {
+	mergedCfg := map[string]any{}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath
+	}
+
+	if !r.noEditorConfig {
+		r.mu.Lock()
+		def, err := r.ecCfg.Load(absPath)
+		r.mu.Unlock()
+		// Ignore errors for best-effort features like editorconfig loading.
+		if err == nil {
+			fillEditorConfig(def, mergedCfg)
+		}
+	}
+
+	userCfg, cfgPath, err := r.userConfig(ctx, filepath.Dir(absPath))
+	if err != nil {
+		return nil, err
+	}
+
+	mergePrettierConfig(mergedCfg, userCfg, cfgPath, absPath)
+	r.applyCLIOptions(mergedCfg, userCfg)
+
+	mergedCfg["filepath"] = filePath
+	return mergedCfg, nil
+}
+
+// resolveDir returns the options to pass to prettier for content without a file
+// path, such as stdin without --stdin-filepath. Only config that does not depend
+// on the file name applies.
+func (r *configResolver) resolveDir(ctx context.Context, dir string) (map[string]any, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		absDir = dir
+	}
+
+	userCfg, _, err := r.userConfig(ctx, absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedCfg := map[string]any{}
+	for k, v := range userCfg {
+		if k != "overrides" {
+			mergedCfg[k] = v
+		}
+	}
+	r.applyCLIOptions(mergedCfg, userCfg)
+
+	return mergedCfg, nil
+}
+
+// applyCLIOptions merges CLI options into mergedCfg, which contains the config
+// resolved from files, following the config precedence.
+func (r *configResolver) applyCLIOptions(mergedCfg map[string]any, userCfg map[string]any) {
+	switch r.configPrecedence {
+	case ConfigPrecedenceFileOverride:
+		for k, v := range r.cliOptions {
+			if _, ok := mergedCfg[k]; !ok {
+				mergedCfg[k] = v
+			}
+		}
+	case ConfigPrecedencePreferFile:
+		// CLI options are only used if there is no config, including editorconfig.
+		if len(mergedCfg) == 0 && userCfg == nil {
+			maps.Copy(mergedCfg, r.cliOptions)
+		}
+	default:
+		maps.Copy(mergedCfg, r.cliOptions)
+	}
+
+	if r.parser != "" {
+		mergedCfg["parser"] = r.parser
+	}
+}
+
+// userConfig returns the prettier config that applies to files in dir and the
+// path of the file it was loaded from.
+func (r *configResolver) userConfig(ctx context.Context, dir string) (map[string]any, string, error) {
+	switch {
+	case r.explicit != nil:
+		return r.explicit, r.explicitPath, nil
+	case r.noConfig:
+		return nil, "", nil
+	}
+
+	p := r.configFile(dir)
+	if p == "" {
+		return nil, "", nil
+	}
+
+	r.mu.Lock()
+	lc, ok := r.configs[p]
+	if !ok {
+		lc = &loadedConfig{}
+		r.configs[p] = lc
+	}
+	r.mu.Unlock()
+
+	lc.once.Do(func() {
+		lc.cfg, lc.err = r.loadConfig(ctx, p)
+	})
+
+	return lc.cfg, p, lc.err
+}
+
+// loadConfig loads the config file at path and validates it.
+func (r *configResolver) loadConfig(ctx context.Context, path string) (map[string]any, error) {
+	if r.runner == nil {
+		return loadConfigFile(ctx, path, nil)
+	}
+	cfg, err := loadConfigFile(ctx, path, r.runner.evalJSConfig)
+	if err != nil {
+		return cfg, err
+	}
+	info, err := r.runner.getSupportInfo(ctx)
+	if err != nil {
+		return cfg, err
+	}
+	return validateConfig(ctx, info, path, cfg)
+}
+
+// configFile returns the path to the config file closest to dir, or an empty
+// string if there is none.
+func (r *configResolver) configFile(dir string) string {
+	r.mu.Lock()
+	p, ok := r.dirs[dir]
+	r.mu.Unlock()
+	if ok {
+		return p
+	}
+
+	p = configFileInDir(dir)
+	if p == "" {
+		if parent := filepath.Dir(dir); parent != dir && parent != "" {
+			p = r.configFile(parent)
+		}
+	}
+
+	r.mu.Lock()
+	r.dirs[dir] = p
+	r.mu.Unlock()
+
+	return p
+}
+
+// configFileInDir returns the path to the highest precedence config file in dir,
+// or an empty string if there is none.
+func configFileInDir(dir string) string {
+	for _, name := range configFileNames {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if isPackageFile(p) && !hasPrettierKey(p) {
+			continue
+		}
+		return p
+	}
+	return ""
+}
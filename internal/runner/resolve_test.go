@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigResolver(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".prettierrc":             "tabWidth: 4\nsemi: false\n",
+		".editorconfig":           "root = true\n\n[*]\nindent_style = space\nindent_size = 8\nmax_line_length = 100\n",
+		"docs/.prettierrc.json":   `{"proseWrap": "always"}`,
+		"deploy/.editorconfig":    "[*.yaml]\nindent_size = 2\n",
+		"deploy/helm/.gitkeep":    "",
+		"vendor/.editorconfig":    "root = true\n\n[*]\nindent_style = tab\n",
+		"vendor/lib/package.json": `{"name": "lib"}`,
+		"site/.prettierrc.yaml":   "overrides:\n  - files: [\"docs/**/*.md\"]\n    options:\n      proseWrap: always\n",
+		"api/.prettierrc.toml":    "[[overrides]]\nfiles = [\"*.md\"]\nexcludeFiles = \"CHANGELOG.md\"\n[overrides.options]\ntabWidth = 3\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	tests := []struct {
+		name string
+		args RunArgs
+		path string
+		exp  map[string]any
+	}{
+		{
+			name: "root",
+			path: "README.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   4,
+				"printWidth": 100,
+				"semi":       false,
+			},
+		},
+		{
+			name: "nested config",
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+				"proseWrap":  "always",
+			},
+		},
+		{
+			name: "editorconfig cascade",
+			path: "deploy/helm/values.yaml",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   4,
+				"printWidth": 100,
+				"semi":       false,
+			},
+		},
+		{
+			name: "editorconfig root",
+			path: "vendor/lib/main.js",
+			exp: map[string]any{
+				"useTabs":  true,
+				"tabWidth": 4,
+				"semi":     false,
+			},
+		},
+		{
+			name: "no config",
+			args: RunArgs{NoConfig: true},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+			},
+		},
+		{
+			name: "no editorconfig",
+			args: RunArgs{NoEditorConfig: true},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"proseWrap": "always",
+			},
+		},
+		{
+			name: "cli override",
+			args: RunArgs{Options: map[string]any{"tabWidth": 3, "proseWrap": "never"}},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   3,
+				"printWidth": 100,
+				"proseWrap":  "never",
+			},
+		},
+		{
+			name: "file override",
+			args: RunArgs{Options: map[string]any{"tabWidth": 3, "proseWrap": "never", "semi": false}, ConfigPrecedence: ConfigPrecedenceFileOverride},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+				"proseWrap":  "always",
+				"semi":       false,
+			},
+		},
+		{
+			name: "prefer file",
+			args: RunArgs{Options: map[string]any{"tabWidth": 3}, ConfigPrecedence: ConfigPrecedencePreferFile},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+				"proseWrap":  "always",
+			},
+		},
+		{
+			name: "prefer file without config",
+			args: RunArgs{Options: map[string]any{"tabWidth": 3}, ConfigPrecedence: ConfigPrecedencePreferFile, NoConfig: true, NoEditorConfig: true},
+			path: "docs/guide/intro.md",
+			exp: map[string]any{
+				"tabWidth": 3,
+			},
+		},
+		{
+			name: "yaml overrides",
+			path: "site/docs/guide/intro.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+				"proseWrap":  "always",
+			},
+		},
+		{
+			name: "yaml overrides relative to config",
+			path: "site/README.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+			},
+		},
+		{
+			name: "toml overrides",
+			path: "api/README.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   int64(3),
+				"printWidth": 100,
+			},
+		},
+		{
+			name: "toml overrides exclude",
+			path: "api/CHANGELOG.md",
+			exp: map[string]any{
+				"useTabs":    false,
+				"tabWidth":   8,
+				"printWidth": 100,
+			},
+		},
+		{
+			name: "explicit config",
+			args: RunArgs{Config: filepath.Join(dir, "docs", ".prettierrc.json"), NoEditorConfig: true},
+			path: "README.md",
+			exp: map[string]any{
+				"proseWrap": "always",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := newConfigResolver(t.Context(), tc.args, nil)
+			require.NoError(t, err)
+
+			path := filepath.Join(dir, tc.path)
+			res, err := r.resolve(t.Context(), path)
+			require.NoError(t, err)
+
+			tc.exp["filepath"] = path
+			require.Equal(t, tc.exp, res)
+		})
+	}
+}
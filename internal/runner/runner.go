@@ -1,29 +1,24 @@
 package runner
 
 import (
-	"bufio"
 	"bytes"
 	"context"
-	"crypto/rand"
-	"encoding/json"
 	"errors"
 	"fmt"
-	gofmt "go/format"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"sync/atomic"
 
-	"github.com/BurntSushi/toml"
-	"github.com/editorconfig/editorconfig-core-go/v2"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
-	"github.com/tetratelabs/wazero/sys"
 	"golang.org/x/sync/errgroup"
-	"gopkg.in/yaml.v3"
 
+	"github.com/wasilibs/go-prettier/v3/internal/gitignore"
 	"github.com/wasilibs/go-prettier/v3/internal/wasm"
 )
 
@@ -32,6 +27,42 @@ var (
 	errInvalidConfigFile = errors.New("invalid config file")
 )
 
+// ErrNoParser is returned when no parser could be inferred for a file.
+var ErrNoParser = errors.New("runner: no parser could be inferred")
+
+// ErrIgnored is returned by FormatFile when the file is matched by an ignore file.
+var ErrIgnored = errors.New("runner: file is ignored")
+
+// SyntaxError is returned when prettier fails to parse the content being formatted.
+type SyntaxError struct {
+	// Path is the path of the file that failed to parse.
+	Path string
+	// Message is the error message reported by prettier.
+	Message string
+	// Loc is the location of the error in the content, if known.
+	Loc *Location
+}
+
+// Error implements error.
+func (e *SyntaxError) Error() string {
+	if e.Loc != nil {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Loc.Start.Line, e.Loc.Start.Column, e.Message)
+	}
+	return fmt.Sprintf("%s: SyntaxError: %s", e.Path, e.Message)
+}
+
+// Location is a range in content being formatted.
+type Location struct {
+	Start Position  `json:"start"`
+	End   *Position `json:"end,omitempty"`
+}
+
+// Position is a position in content being formatted. Lines and columns start at 1.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
 func NewRunner() *Runner {
 	ctx := context.Background()
 
@@ -54,320 +85,484 @@ func NewRunner() *Runner {
 	}
 
 	return &Runner{
-		compiled: compiled,
-		rt:       rt,
+		rt:   rt,
+		pool: newPool(rt, compiled, runtime.NumCPU()),
 	}
 }
 
 type Runner struct {
-	compiled wazero.CompiledModule
-	rt       wazero.Runtime
+	rt   wazero.Runtime
+	pool *pool
+
+	// supportInfoMu guards supportInfo, which is only set once fetched
+	// successfully so that errors, such as from a canceled context, are retried.
+	supportInfoMu sync.Mutex
+	supportInfo   *supportInfo
+}
+
+// Format formats in, using cfg as the prettier configuration. Config files
+// are not resolved. ErrNoParser is returned if no parser is specified in cfg
+// and none could be inferred from filePath.
+func (r *Runner) Format(ctx context.Context, in []byte, filePath string, cfg map[string]any) ([]byte, error) {
+	res, _, err := r.FormatWithCursor(ctx, in, filePath, cfg)
+	return res, err
+}
+
+// FormatWithCursor is like Format but also returns the position of the cursor
+// in the formatted content, given its position in in with the cursorOffset
+// option in cfg. Like prettier, offsets count UTF-16 code units, and -1 is
+// returned if there is no cursorOffset.
+func (r *Runner) FormatWithCursor(ctx context.Context, in []byte, filePath string, cfg map[string]any) ([]byte, int, error) {
+	mergedCfg := maps.Clone(cfg)
+	if mergedCfg == nil {
+		mergedCfg = map[string]any{}
+	}
+	mergedCfg["filepath"] = filePath
+
+	res, cursorOffset, err := r.formatContentWithCursor(ctx, in, mergedCfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []byte(res), cursorOffset, nil
+}
+
+// FormatFile formats in as the content of the file at filePath, which does not
+// need to exist. Ignore files and config are resolved for the file like Run does
+// with args, though config is not cached between calls so that edits to config
+// files apply to long-running callers. ErrIgnored is returned if the file is
+// ignored.
+func (r *Runner) FormatFile(ctx context.Context, in []byte, filePath string, args RunArgs) ([]byte, error) {
+	ignores := loadIgnoreFiles(ctx, args)
+	ignores = append(ignores, gitignore.NewMatcher(defaultIgnorePatterns(args)))
+	if ignoreAnyMatch(filePath, ignores, false) {
+		return nil, ErrIgnored
+	}
+
+	resolver, err := newConfigResolver(ctx, args, r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := resolver.resolve(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.formatContent(ctx, in, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(res), nil
+}
+
+// Close releases the resources held by the runner.
+func (r *Runner) Close(ctx context.Context) error {
+	r.pool.close()
+	if err := r.rt.Close(ctx); err != nil {
+		return fmt.Errorf("runner: closing runtime: %w", err)
+	}
+	return nil
 }
 
 type RunArgs struct {
 	Cwd                       string
 	Patterns                  []string
 	Config                    string
+	ConfigPrecedence          string
+	Options                   map[string]any
+	Parser                    string
 	NoConfig                  bool
 	NoEditorConfig            bool
 	Check                     bool
+	ListDifferent             bool
 	IgnorePaths               []string
 	IgnoreUnknown             bool
 	Write                     bool
 	WithNodeModules           bool
 	NoErrorOnUnmatchedPattern bool
+	Stdin                     bool
 	StdinFilepath             string
+	FindConfigPath            string
+	FileInfo                  string
+	PrintConfig               string
+	Cache                     bool
+	CacheLocation             string
+	CacheStrategy             string
+	// Reporter, if set, replaces the human-readable output of a run with a
+	// machine-readable report of each file's result written to stdout.
+	Reporter string
+	// Diff prints a unified diff for each file that is not formatted.
+	Diff bool
+	// DiffContext is the number of unchanged lines shown around each change in a diff.
+	DiffContext int
+	// DiffColor colorizes diffs, which should only be set when stdout is a terminal
+	// so that diffs can be redirected to a patch file.
+	DiffColor bool
+	// Watch keeps running after formatting, formatting files again when they change.
+	Watch bool
+	// ChangedSince restricts formatting to files that differ from this git revision.
+	ChangedSince string
+	// Staged restricts formatting to files with changes staged in the git index.
+	// With Write, the staged content is formatted and staged again instead of the
+	// working tree file, which is only updated if it has no unstaged changes.
+	Staged bool
 }
 
 func (r *Runner) Run(ctx context.Context, args RunArgs) error {
-	var eCfg *editorconfig.Editorconfig
-
-	// We use an untyped map for prettier config to allow piping through user config
-	// without needing to recognizing every option.
-	pCfg := map[string]any{}
-
-	if !args.NoEditorConfig {
-		eCfgPath := findConfigFile(args.Cwd, ".editorconfig")
-		if eCfgPath != "" {
-			f, err := os.Open(eCfgPath) //nolint:gosec
-			// Ignore errors for best-effort features like editorconfig loading.
-			if err == nil {
-				if c, err := editorconfig.Parse(f); err == nil {
-					eCfg = c
-				}
-			}
-		}
+	resolver, err := newConfigResolver(ctx, args, r)
+	if err != nil {
+		return err
 	}
 
 	switch {
-	case args.Config != "":
-		cfg, err := loadConfigFile(ctx, args.Config)
-		if err != nil {
-			return err
-		}
-		pCfg = cfg
-	case args.NoConfig:
-		// Do nothing
-	default:
-		for _, name := range []string{".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml", ".prettierrc.toml"} {
-			if p := findConfigFile(args.Cwd, name); p != "" {
-				cfg, err := loadConfigFile(ctx, p)
-				if err != nil {
-					return err
-				}
-				pCfg = cfg
-				break
-			}
-		}
+	case args.FindConfigPath != "":
+		return r.findConfigPath(ctx, args, resolver)
+	case args.FileInfo != "":
+		return r.fileInfo(ctx, args, resolver)
+	case args.PrintConfig != "":
+		return r.printConfig(ctx, args, resolver)
 	}
 
-	if args.StdinFilepath != "" {
+	if args.Stdin || args.StdinFilepath != "" {
+		if args.StdinFilepath == "" && args.Parser == "" {
+			err := errors.New("No parser and no file path given, couldn't infer a parser.") //nolint:staticcheck // Match upstream message
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
 		in, err := io.ReadAll(os.Stdin)
 		if err != nil {
 			return fmt.Errorf("runner: reading stdin: %w", err)
 		}
-		res, inferred, err := r.formatContent(ctx, in, args.StdinFilepath, eCfg, pCfg)
-		if err != nil {
+		res, cursorOffset, err := r.formatStdin(ctx, args, resolver, in)
+		return writeStdinResult(ctx, args, in, res, cursorOffset, err)
+	}
+
+	args.Patterns = defaultPatterns(args)
+	paths, err := filterChanged(ctx, args, expandPatterns(ctx, args))
+	if err != nil {
+		slog.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	var staged *stagedIndex
+	if args.Staged && args.Write {
+		if staged, err = openStagedIndex(args.Cwd); err != nil {
+			slog.ErrorContext(ctx, err.Error())
 			return err
 		}
-		if !inferred {
-			if !args.IgnoreUnknown {
-				slog.WarnContext(ctx, fmt.Sprintf(`No parser could be inferred for file "%s".`, args.StdinFilepath))
-			}
-			// Echo input unchanged so the filter round-trips safely.
-			_, _ = os.Stdout.Write(in)
-			return nil
-		}
-		fmt.Print(res)
-		return nil
 	}
 
-	paths := expandPatterns(ctx, args)
+	cacheLocation := cacheFilePath(args)
 
-	if args.Check {
+	var cache *formatCache
+	if args.Cache {
+		strategy := args.CacheStrategy
+		if strategy == "" {
+			strategy = CacheStrategyContent
+		}
+		cache = loadFormatCache(ctx, cacheLocation, strategy)
+	} else if args.CacheLocation == "" {
+		// Match upstream, which removes a stale cache when caching is disabled.
+		_ = os.Remove(cacheLocation)
+	}
+
+	if args.Check && args.Reporter == "" {
 		fmt.Println("Checking formatting...")
 	}
 
 	var numCheckFailed atomic.Uint32
 
+	var resultsMu sync.Mutex
+	var results []fileResult
+
 	var g errgroup.Group
 	g.SetLimit(runtime.NumCPU())
 	for _, p := range paths {
 		g.Go(func() error {
 			if p.error != "" {
 				slog.ErrorContext(ctx, p.error)
+				if args.Reporter != "" {
+					resultsMu.Lock()
+					results = append(results, fileResult{Status: fileStatusError, Message: p.error})
+					resultsMu.Unlock()
+				}
 				return errors.New(p.error)
 			}
-			err := r.format(ctx, p, eCfg, pCfg, args.Check, args.Write, args.IgnoreUnknown)
-			if errors.Is(err, errCheckFailed) {
+			var res fileResult
+			var err error
+			if staged != nil {
+				res, err = r.formatStaged(ctx, p, resolver, args, staged)
+			} else {
+				res, err = r.format(ctx, p, resolver, args, cache)
+			}
+			if res.Status == fileStatusFormatted {
 				numCheckFailed.Add(1)
 			}
+			if args.Reporter != "" {
+				resultsMu.Lock()
+				results = append(results, res)
+				resultsMu.Unlock()
+			}
 			return err
 		})
 	}
-	err := g.Wait()
+	err = g.Wait()
+
+	if staged != nil {
+		if err := staged.commit(); err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+	}
+
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			slog.WarnContext(ctx, err.Error())
+		}
+	}
+
+	if args.Reporter != "" {
+		if err := writeReport(os.Stdout, args.Reporter, results, args); err != nil {
+			slog.ErrorContext(ctx, err.Error())
+			return err
+		}
+	}
 
 	if args.Check {
-		if n := numCheckFailed.Load(); n > 0 {
+		if n := numCheckFailed.Load(); n > 0 && args.Write {
+			slog.Warn(fmt.Sprintf("Code style issues fixed in %d files.", n))
+		} else if n > 0 {
 			slog.Warn(fmt.Sprintf("Code style issues found in %d files. Run Prettier to fix.", n))
-		} else {
+		} else if args.Reporter == "" {
 			fmt.Println("All matched files use Prettier code style!")
 		}
 	}
 
+	if args.Watch {
+		return r.watch(ctx, args, cache)
+	}
+
 	return err
 }
 
+// formatStdin formats in, read from stdin, returning the formatted content and
+// cursor position, which is -1 if no cursorOffset was requested.
+func (r *Runner) formatStdin(ctx context.Context, args RunArgs, resolver *configResolver, in []byte) (string, int, error) {
+	var cfg map[string]any
+	var err error
+	if args.StdinFilepath != "" {
+		cfg, err = resolver.resolve(ctx, filepath.Join(args.Cwd, args.StdinFilepath))
+		if err == nil {
+			cfg["filepath"] = args.StdinFilepath
+		}
+	} else {
+		cfg, err = resolver.resolveDir(ctx, args.Cwd)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	res, cursorOffset, err := r.formatContentWithCursor(ctx, in, cfg)
+	if _, ok := cfg["cursorOffset"]; !ok {
+		cursorOffset = -1
+	}
+	return res, cursorOffset, err
+}
+
+// writeStdinResult writes the result of formatStdin, logging any error.
+func writeStdinResult(ctx context.Context, args RunArgs, in []byte, res string, cursorOffset int, err error) error {
+	if errors.Is(err, ErrNoParser) {
+		if !args.IgnoreUnknown {
+			slog.WarnContext(ctx, fmt.Sprintf(`No parser could be inferred for file "%s".`, args.StdinFilepath))
+		}
+		// Echo input unchanged so the filter round-trips safely.
+		_, _ = os.Stdout.Write(in)
+		return nil
+	}
+	if err != nil {
+		logFormatError(ctx, err, in)
+		return err
+	}
+	writeOutput(res, cursorOffset)
+	return nil
+}
+
 type jsonMsg struct {
-	Name string `json:"name"`
-	Body string `json:"body"`
+	Name   string         `json:"name"`
+	Body   string         `json:"body"`
+	Config map[string]any `json:"config,omitempty"`
+	Error  *jsonError     `json:"error,omitempty"`
+	// Path is the path of the JS config file to evaluate, or of the module a
+	// module request is relative to.
+	Path string `json:"path,omitempty"`
+	// ModuleType is the type of the JS module in the body of an eval-config
+	// message or module response, "module" or "commonjs" as in package.json.
+	ModuleType string `json:"moduleType,omitempty"`
+	// CursorOffset is the position of the cursor in the formatted content of a
+	// result, or -1 if no cursorOffset was requested.
+	CursorOffset int `json:"cursorOffset,omitempty"`
 }
 
-func (r *Runner) format(ctx context.Context, path expandedPath, eCfg *editorconfig.Editorconfig, userCfg map[string]any, check bool, write bool, ignoreUnknown bool) error {
+type jsonError struct {
+	Name    string    `json:"name"`
+	Message string    `json:"message"`
+	Loc     *Location `json:"loc,omitempty"`
+}
+
+func (r *Runner) format(ctx context.Context, path expandedPath, resolver *configResolver, args RunArgs, cache *formatCache) (fileResult, error) {
 	fi, err := os.Stat(path.filePath)
 	if err != nil {
 		slog.WarnContext(ctx, fmt.Sprintf(`Unable to read file "%s"`, path.filePath))
 		slog.WarnContext(ctx, err.Error())
-		return fmt.Errorf("runner: stat-ing file: %w", err)
+		err = fmt.Errorf("runner: stat-ing file: %w", err)
+		return newErrorResult(path.filePath, err), err
 	}
 
 	in, err := os.ReadFile(path.filePath)
 	if err != nil {
 		slog.WarnContext(ctx, fmt.Sprintf(`Unable to read file "%s"`, path.filePath))
 		slog.WarnContext(ctx, err.Error())
-		return fmt.Errorf("runner: reading file: %w", err)
+		err = fmt.Errorf("runner: reading file: %w", err)
+		return newErrorResult(path.filePath, err), err
 	}
 
-	res, inferred, err := r.formatContent(ctx, in, path.filePath, eCfg, userCfg)
+	cfg, err := resolver.resolve(ctx, path.filePath)
 	if err != nil {
-		return err
+		return newErrorResult(path.filePath, err), err
 	}
-	if !inferred {
-		if !ignoreUnknown && !path.ignoreUnknown {
-			slog.WarnContext(ctx, fmt.Sprintf(`No parser could be inferred for file "%s".`, path.filePath))
+	absPath, _ := filepath.Abs(path.filePath)
+
+	var res string
+	cursorOffset := -1
+	if cache != nil && cache.isFormatted(absPath, fi, in, cfg) {
+		res = string(in)
+		cursorOffset = requestedCursorOffset(cfg)
+	} else {
+		res, cursorOffset, err = r.formatContentWithCursor(ctx, in, cfg)
+		if _, ok := cfg["cursorOffset"]; !ok {
+			cursorOffset = -1
 		}
-		return nil
-	}
-
-	if write {
-		if err := os.WriteFile(path.filePath, []byte(res), fi.Mode()); err != nil {
-			return fmt.Errorf("runner: failed to write file: %w", err)
+		if errors.Is(err, ErrNoParser) {
+			if !args.IgnoreUnknown && !path.ignoreUnknown {
+				slog.WarnContext(ctx, fmt.Sprintf(`No parser could be inferred for file "%s".`, path.filePath))
+			}
+			return fileResult{Path: path.filePath, Status: fileStatusUnsupported}, nil
+		}
+		if err != nil {
+			logFormatError(ctx, err, in)
+			return newErrorResult(path.filePath, err), err
 		}
-	} else if !check {
-		fmt.Print(res)
-	}
-
-	if check && !bytes.Equal(in, []byte(res)) {
-		slog.Warn(path.filePath)
-		return errCheckFailed
 	}
 
-	return nil
-}
+	formatted := bytes.Equal(in, []byte(res))
 
-func (r *Runner) formatContent(ctx context.Context, in []byte, filePath string, eCfg *editorconfig.Editorconfig, userCfg map[string]any) (res string, inferred bool, err error) {
-	mergedCfg := map[string]any{}
-	if eCfg != nil {
-		def, err := eCfg.GetDefinitionForFilename(filePath)
-		if err == nil {
-			fillEditorConfig(def, mergedCfg)
+	if args.Write {
+		if !formatted {
+			if err := os.WriteFile(path.filePath, []byte(res), fi.Mode()); err != nil {
+				err = fmt.Errorf("runner: failed to write file: %w", err)
+				return newErrorResult(path.filePath, err), err
+			}
+			if cache != nil {
+				if fi, err := os.Stat(path.filePath); err == nil {
+					cache.setFormatted(absPath, fi, []byte(res), cfg)
+				}
+			}
 		}
+	} else if !args.Check && !args.ListDifferent && !args.Diff && args.Reporter == "" {
+		writeOutput(res, cursorOffset)
 	}
 
-	mergePrettierConfig(mergedCfg, userCfg, filePath)
-
-	mergedCfg["filepath"] = filePath
-	pCfgBytes, err := json.Marshal(mergedCfg)
-	if err != nil {
-		// Programming bug
-		panic(err)
+	if formatted && cache != nil {
+		cache.setFormatted(absPath, fi, in, cfg)
 	}
 
-	stdinR, stdinW := io.Pipe()
-	stdoutR, stdoutW := io.Pipe()
-
-	inMsg := jsonMsg{
-		Name: "input",
-		Body: string(in),
+	if formatted {
+		return fileResult{Path: path.filePath, Status: fileStatusUnchanged}, nil
 	}
 
-	resChan := make(chan string, 1)
-
-	go func() {
-		defer func() {
-			_ = stdinW.Close()
-			_ = stdoutR.Close()
-		}()
-		stdinJW := json.NewEncoder(stdinW)
-		if err := stdinJW.Encode(inMsg); err != nil {
-			panic(fmt.Errorf("runner: encoding input message for prettier: %w", err))
-		}
-		br := bufio.NewReader(stdoutR)
-		for {
-			line, err := br.ReadString('\n')
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					return
-				}
-				panic(err)
-			}
-			var msg jsonMsg
-			if err := json.Unmarshal([]byte(line), &msg); err != nil {
-				panic(fmt.Errorf("runner: unmarshaling message from prettier: %w", err))
-			}
-			switch msg.Name {
-			case "gofmt-request":
-				// TODO: Consider if err needs to be handled.
-				formatted, err := gofmt.Source([]byte(msg.Body))
-				if err != nil {
-					// This should only apply to an embedded string, treat it as best-effort.
-					formatted = []byte(msg.Body)
-				}
-				msg := jsonMsg{
-					Name: "gofmt-response",
-					Body: string(formatted),
-				}
-				if err := stdinJW.Encode(msg); err != nil {
-					panic(fmt.Errorf("runner: encoding gofmt response message for prettier: %w", err))
-				}
-			case "result":
-				resChan <- msg.Body
-				return
-			}
+	result := fileResult{Path: path.filePath, Status: fileStatusFormatted}
+	if args.Diff && args.Reporter == "" {
+		// Print the whole diff at once so diffs of files formatted concurrently
+		// don't interleave.
+		fmt.Print(unifiedDiff(filepath.ToSlash(path.filePath), string(in), res, args.DiffContext, !args.DiffColor))
+	}
+	switch {
+	case args.Check:
+		if args.Reporter == "" {
+			slog.Warn(path.filePath)
 		}
-	}()
-
-	mCfg := wazero.NewModuleConfig().
-		WithName("").
-		WithSysNanosleep().
-		WithSysNanotime().
-		WithSysWalltime().
-		WithRandSource(rand.Reader).
-		WithArgs("prettier", string(pCfgBytes)).
-		WithStdin(stdinR).
-		WithStderr(stdoutW). // Use stderr for communication to avoid buffering challenges
-		WithStdout(os.Stderr)
-
-	mod, err := r.rt.InstantiateModule(ctx, r.compiled, mCfg)
-	if err != nil {
-		if se, ok := err.(*sys.ExitError); ok { //nolint:errorlint
-			if se.ExitCode() == 10 {
-				return "", false, nil
-			}
+	case args.ListDifferent:
+		if args.Reporter == "" {
+			fmt.Println(path.filePath)
 		}
-		err = fmt.Errorf("runner: failed to run prettier [%s]: %w", filePath, err)
-		fmt.Println(err)
-		return "", false, err
 	}
-	defer func() {
-		_ = mod.Close(ctx)
-	}()
+	// Like upstream, files that were fixed with --write aren't a failure.
+	if (args.Check || args.ListDifferent || args.Diff) && !args.Write {
+		return result, errCheckFailed
+	}
 
-	return <-resChan, true, nil
+	return result, nil
 }
 
-func findConfigFile(cwd string, name string) string {
-	dir, err := filepath.Abs(cwd)
-	if err != nil {
-		return ""
+// requestedCursorOffset returns the cursorOffset option in cfg, which is a float64
+// when set in a config file, or -1 if it isn't set.
+func requestedCursorOffset(cfg map[string]any) int {
+	if n, ok := toNumber(cfg["cursorOffset"]); ok {
+		return int(n)
 	}
+	return -1
+}
 
-	for {
-		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
-			return filepath.Join(dir, name)
-		}
+// writeOutput prints formatted content to stdout. Like upstream, the new cursor
+// position is printed to stderr when a cursorOffset was requested, otherwise
+// cursorOffset is -1.
+func writeOutput(res string, cursorOffset int) {
+	fmt.Print(res)
+	if cursorOffset >= 0 {
+		fmt.Fprintln(os.Stderr, cursorOffset)
+	}
+}
 
-		parent := filepath.Dir(dir)
-		if parent == dir || parent == "" {
-			return ""
+// logFormatError logs an error from formatting in, including a code frame
+// pointing at the location of syntax errors.
+func logFormatError(ctx context.Context, err error, in []byte) {
+	msg := err.Error()
+	var se *SyntaxError
+	if errors.As(err, &se) && se.Loc != nil {
+		if frame := codeFrame(string(in), se.Loc); frame != "" {
+			msg += "\n" + frame
 		}
-
-		dir = parent
 	}
+	slog.ErrorContext(ctx, msg)
 }
 
-func loadConfigFile(ctx context.Context, path string) (map[string]any, error) {
-	res := map[string]any{}
+func (r *Runner) formatContent(ctx context.Context, in []byte, cfg map[string]any) (string, error) {
+	res, _, err := r.formatContentWithCursor(ctx, in, cfg)
+	return res, err
+}
 
-	pCfgBytes, err := os.ReadFile(path) //nolint:gosec
-	if err != nil {
-		slog.WarnContext(ctx, fmt.Sprintf(`Unable to read config file "%s"`, path))
-		slog.WarnContext(ctx, err.Error())
-		return res, fmt.Errorf("runner: reading config file: %w", err)
-	}
+func (r *Runner) formatContentWithCursor(ctx context.Context, in []byte, cfg map[string]any) (string, int, error) {
+	filePath, _ := cfg["filepath"].(string)
 
-	// YAML is superset of JSON so it should be fine to only use YAML to parse.
-	err = yaml.Unmarshal(pCfgBytes, &res)
-	if err == nil {
-		return res, nil
+	msg, err := r.pool.request(ctx, jsonMsg{
+		Name:   "format",
+		Body:   string(in),
+		Config: cfg,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("runner: failed to run prettier [%s]: %w", filePath, err)
 	}
 
-	if err := toml.Unmarshal(pCfgBytes, &res); err == nil {
-		return res, nil
+	switch {
+	case msg.Name == "result":
+		return msg.Body, msg.CursorOffset, nil
+	case msg.Name == "error" && msg.Error != nil:
+		switch msg.Error.Name {
+		case "UndefinedParserError":
+			return "", 0, ErrNoParser
+		case "SyntaxError":
+			return "", 0, &SyntaxError{Path: filePath, Message: msg.Error.Message, Loc: msg.Error.Loc}
+		}
+		return "", 0, fmt.Errorf("runner: %s: %s: %s", filePath, msg.Error.Name, msg.Error.Message)
 	}
 
-	slog.WarnContext(ctx, fmt.Sprintf(`Invalid config file "%s"`, path))
-	// JSON / YAML are more common so use it's error rather than TOML's
-	slog.WarnContext(ctx, err.Error())
-	return res, errInvalidConfigFile
+	return "", 0, fmt.Errorf("runner: unexpected message from prettier [%s]: %s", filePath, msg.Name)
 }
@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wasilibs/go-prettier/v3/internal/git"
+)
+
+// stagedIndex formats the content staged in the index rather than the working
+// tree for --staged --write, as a pre-commit hook, like pretty-quick.
+type stagedIndex struct {
+	repo *git.Repo
+
+	mu      sync.Mutex
+	idx     *git.LockedIndex
+	changed bool
+}
+
+// openStagedIndex locks the index of the repository containing cwd until commit
+// is called.
+func openStagedIndex(cwd string) (*stagedIndex, error) {
+	repo, err := git.Find(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("runner: finding git repository: %w", err)
+	}
+	idx, err := repo.LockIndex()
+	if err != nil {
+		return nil, fmt.Errorf("runner: locking git index: %w", err)
+	}
+	return &stagedIndex{repo: repo, idx: idx}, nil
+}
+
+// entry returns the index entry for the file at path. It must be called with
+// s.mu held.
+func (s *stagedIndex) entry(path string) (*git.IndexEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("runner: resolving path: %w", err)
+	}
+	rel, err := filepath.Rel(s.repo.Root, abs)
+	if err != nil {
+		return nil, fmt.Errorf("runner: resolving path: %w", err)
+	}
+	e := s.idx.Entry(filepath.ToSlash(rel))
+	if e == nil {
+		return nil, fmt.Errorf("runner: %s is not staged", path)
+	}
+	return e, nil
+}
+
+// read returns the staged content of the file at path.
+func (s *stagedIndex) read(path string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.entry(path)
+	if err != nil {
+		return nil, err
+	}
+	_, b, err := s.repo.ReadObject(e.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("runner: reading staged file: %w", err)
+	}
+	return b, nil
+}
+
+// update stages res as the content of the file at path, which had the staged
+// content in. The working tree file is only updated if it has no unstaged
+// changes, so that they are not lost.
+func (s *stagedIndex) update(ctx context.Context, path string, in, res []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.entry(path)
+	if err != nil {
+		return err
+	}
+	h, err := s.repo.WriteObject(git.TypeBlob, res)
+	if err != nil {
+		return fmt.Errorf("runner: writing staged file: %w", err)
+	}
+	e.Hash = h
+	s.changed = true
+
+	wt, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(wt, in) {
+		// Besides unstaged changes, the working tree file also differs when git
+		// converts content, such as line endings with core.autocrlf or a clean
+		// filter, which we don't apply.
+		slog.WarnContext(ctx, fmt.Sprintf(`Formatted the staged content of "%s" but not the file in the working tree, which differs from it.`, path))
+		// The stat data matches the working tree file, which now differs from the
+		// index, so make sure git compares the content.
+		e.Invalidate()
+		return nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("runner: stat-ing file: %w", err)
+	}
+	if err := os.WriteFile(path, res, fi.Mode()); err != nil {
+		e.Invalidate()
+		return fmt.Errorf("runner: failed to write file: %w", err)
+	}
+	if fi, err := os.Stat(path); err == nil {
+		e.SetStat(fi)
+	} else {
+		e.Invalidate()
+	}
+	return nil
+}
+
+// commit writes the index if any files were formatted and unlocks it.
+func (s *stagedIndex) commit() error {
+	if !s.changed {
+		s.idx.Unlock()
+		return nil
+	}
+	if err := s.idx.Commit(); err != nil {
+		return fmt.Errorf("runner: writing git index: %w", err)
+	}
+	return nil
+}
+
+// formatStaged formats the staged content of the file at path, staging the
+// result.
+func (r *Runner) formatStaged(ctx context.Context, path expandedPath, resolver *configResolver, args RunArgs, staged *stagedIndex) (fileResult, error) {
+	in, err := staged.read(path.filePath)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf(`Unable to read file "%s"`, path.filePath))
+		slog.WarnContext(ctx, err.Error())
+		return newErrorResult(path.filePath, err), err
+	}
+
+	cfg, err := resolver.resolve(ctx, path.filePath)
+	if err != nil {
+		return newErrorResult(path.filePath, err), err
+	}
+
+	res, err := r.formatContent(ctx, in, cfg)
+	if errors.Is(err, ErrNoParser) {
+		if !args.IgnoreUnknown && !path.ignoreUnknown {
+			slog.WarnContext(ctx, fmt.Sprintf(`No parser could be inferred for file "%s".`, path.filePath))
+		}
+		return fileResult{Path: path.filePath, Status: fileStatusUnsupported}, nil
+	}
+	if err != nil {
+		logFormatError(ctx, err, in)
+		return newErrorResult(path.filePath, err), err
+	}
+
+	if bytes.Equal(in, []byte(res)) {
+		return fileResult{Path: path.filePath, Status: fileStatusUnchanged}, nil
+	}
+
+	if err := staged.update(ctx, path.filePath, in, []byte(res)); err != nil {
+		return newErrorResult(path.filePath, err), err
+	}
+	if args.Diff && args.Reporter == "" {
+		fmt.Print(unifiedDiff(filepath.ToSlash(path.filePath), string(in), res, args.DiffContext, !args.DiffColor))
+	}
+	return fileResult{Path: path.filePath, Status: fileStatusFormatted}, nil
+}
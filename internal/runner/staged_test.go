@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wasilibs/go-prettier/v3/internal/git/gittest"
+)
+
+func TestStagedIndex(t *testing.T) {
+	dir, git := gittest.NewRepo(t)
+
+	gittest.WriteFile(t, dir, "clean.md", "# clean\n")
+	gittest.WriteFile(t, dir, "partial.md", "# partial\n")
+	git("add", ".")
+	gittest.WriteFile(t, dir, "partial.md", "# partial\n\nunstaged\n")
+
+	staged, err := openStagedIndex(dir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	for _, name := range []string{"clean.md", "partial.md"} {
+		p := filepath.Join(dir, name)
+		in, err := staged.read(p)
+		require.NoError(t, err)
+		require.Equal(t, "# "+name[:len(name)-3]+"\n", string(in))
+		require.NoError(t, staged.update(t.Context(), p, in, []byte("formatted "+name+"\n")))
+	}
+	_, err = staged.read(filepath.Join(dir, "missing.md"))
+	require.ErrorContains(t, err, "not staged")
+
+	require.NoError(t, staged.commit())
+
+	// Only skipping the working tree file is reported.
+	require.Equal(t, fmt.Sprintf(`Formatted the staged content of "%s" but not the file in the working tree, which differs from it.`+"\n",
+		filepath.Join(dir, "partial.md")), logMessages(t, &buf))
+
+	require.Equal(t, "formatted clean.md", git("show", ":clean.md"))
+	require.Equal(t, "formatted partial.md", git("show", ":partial.md"))
+
+	// Files without unstaged changes are formatted in the working tree too.
+	b, err := os.ReadFile(filepath.Join(dir, "clean.md"))
+	require.NoError(t, err)
+	require.Equal(t, "formatted clean.md\n", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "partial.md"))
+	require.NoError(t, err)
+	require.Equal(t, "# partial\n\nunstaged\n", string(b))
+	require.Equal(t, "partial.md", git("diff", "--name-only"))
+
+	// The index is unlocked.
+	staged, err = openStagedIndex(dir)
+	require.NoError(t, err)
+	require.NoError(t, staged.commit())
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// supportInfo is the subset of the result of prettier's getSupportInfo used by
+// the runner, covering prettier core and the bundled plugins.
+type supportInfo struct {
+	Options []supportOption `json:"options"`
+}
+
+type supportOption struct {
+	Name    string               `json:"name"`
+	Type    string               `json:"type"`
+	Array   bool                 `json:"array"`
+	Choices []supportOptionValue `json:"choices"`
+	Range   *supportOptionRange  `json:"range"`
+}
+
+// supportOptionRange is the range of an int option. Bounds are nil when
+// unbounded since upstream uses Infinity, which is encoded as null in JSON.
+type supportOptionRange struct {
+	Start *float64 `json:"start"`
+	End   *float64 `json:"end"`
+}
+
+type supportOptionValue struct {
+	Value any `json:"value"`
+}
+
+func (r *Runner) getSupportInfo(ctx context.Context) (*supportInfo, error) {
+	r.supportInfoMu.Lock()
+	defer r.supportInfoMu.Unlock()
+
+	if r.supportInfo != nil {
+		return r.supportInfo, nil
+	}
+
+	msg, err := r.pool.request(ctx, jsonMsg{Name: "support-info"})
+	if err != nil {
+		return nil, fmt.Errorf("runner: failed to run prettier: %w", err)
+	}
+	if msg.Name != "result" {
+		return nil, fmt.Errorf("runner: unexpected message from prettier: %s", msg.Name)
+	}
+	var info supportInfo
+	if err := json.Unmarshal([]byte(msg.Body), &info); err != nil {
+		return nil, fmt.Errorf("runner: unmarshaling support info: %w", err)
+	}
+	r.supportInfo = &info
+	return r.supportInfo, nil
+}
+
+// Parsers returns the names of the parsers supported by prettier and the bundled
+// plugins.
+func (r *Runner) Parsers(ctx context.Context) ([]string, error) {
+	info, err := r.getSupportInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, o := range info.Options {
+		if o.Name != "parser" {
+			continue
+		}
+		for _, c := range o.Choices {
+			if s, ok := c.Value.(string); ok {
+				res = append(res, s)
+			}
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res), nil
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSupportInfoRetries(t *testing.T) {
+	p := newPool(nil, nil, 1)
+	p.idle <- fakeInstance(
+		jsonMsg{Name: "error", Error: &jsonError{Name: "Error", Message: "failed"}},
+		jsonMsg{Name: "result", Body: testSupportInfo},
+	)
+	r := &Runner{pool: p}
+	defer p.close()
+
+	_, err := r.getSupportInfo(t.Context())
+	require.Error(t, err)
+
+	// The error is not cached.
+	info, err := r.getSupportInfo(t.Context())
+	require.NoError(t, err)
+	require.NotNil(t, info.option("tabWidth"))
+
+	// The result is cached, the guest has no more replies.
+	cached, err := r.getSupportInfo(t.Context())
+	require.NoError(t, err)
+	require.Same(t, info, cached)
+}
+
+// fakeInstance returns an instance whose guest replies to each request with the
+// next of replies, exiting after the last.
+func fakeInstance(replies ...jsonMsg) *instance {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	inst := &instance{
+		stdin:  stdinW,
+		stdout: stdoutR,
+		enc:    json.NewEncoder(stdinW),
+		out:    bufio.NewReader(stdoutR),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(inst.done)
+		dec := json.NewDecoder(stdinR)
+		enc := json.NewEncoder(stdoutW)
+		for _, res := range replies {
+			var req jsonMsg
+			if err := dec.Decode(&req); err != nil {
+				break
+			}
+			if err := enc.Encode(res); err != nil {
+				break
+			}
+		}
+		_ = stdoutW.CloseWithError(io.EOF)
+		_ = stdinR.Close()
+	}()
+	return inst
+}
@@ -0,0 +1,317 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// https://github.com/prettier/prettier/blob/main/src/main/normalize-options.js
+
+// validateConfig checks the options in cfg, loaded from the config file at path,
+// against the options supported by prettier and the bundled plugins, including
+// the options of each override. Like upstream, unknown options are removed with
+// a warning and invalid values are an error. cfg is not modified, the options to
+// use are returned in a copy.
+func validateConfig(ctx context.Context, info *supportInfo, path string, cfg map[string]any) (map[string]any, error) {
+	res, err := validateOptions(ctx, info, path, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	overrides := toMaps(cfg["overrides"])
+	if overrides == nil {
+		return res, nil
+	}
+	validOverrides := make([]any, 0, len(overrides))
+	for _, o := range overrides {
+		if opts := toMap(o["options"]); opts != nil {
+			valid, err := validateOptions(ctx, info, path, opts, false)
+			if err != nil {
+				return nil, err
+			}
+			o = maps.Clone(o)
+			o["options"] = valid
+		}
+		validOverrides = append(validOverrides, o)
+	}
+	res["overrides"] = validOverrides
+	return res, nil
+}
+
+// validateOptions returns a copy of opts without unknown options, or an error
+// if an option has an invalid value.
+func validateOptions(ctx context.Context, info *supportInfo, path string, opts map[string]any, topLevel bool) (map[string]any, error) {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	// Report problems in a stable order since maps are unordered.
+	slices.Sort(keys)
+
+	res := make(map[string]any, len(opts))
+	for _, k := range keys {
+		switch k {
+		case "overrides":
+			if topLevel {
+				res[k] = opts[k]
+				continue
+			}
+		case "$schema":
+			// Editors add a schema to JSON configs for completion, which prettier
+			// itself ignores.
+			res[k] = opts[k]
+			continue
+		}
+
+		o := info.option(k)
+		if o == nil {
+			msg := fmt.Sprintf("Ignored unknown option %s.", describePair(k, opts[k]))
+			if s := info.suggest(k); s != "" {
+				msg += fmt.Sprintf(" Did you mean %s?", describeKey(s))
+			}
+			slog.WarnContext(ctx, msg)
+			continue
+		}
+
+		if expected, ok := o.validate(opts[k]); !ok {
+			slog.ErrorContext(ctx, fmt.Sprintf(`Invalid config file "%s"`, path))
+			slog.ErrorContext(ctx, fmt.Sprintf("Invalid %s value. Expected %s, but received %s.",
+				describeKey(k), expected, describeValue(opts[k])))
+			return nil, errInvalidConfigFile
+		}
+		res[k] = opts[k]
+	}
+	return res, nil
+}
+
+// option returns the supported option named name, or nil if there is none.
+func (i *supportInfo) option(name string) *supportOption {
+	for j := range i.Options {
+		if i.Options[j].Name == name {
+			return &i.Options[j]
+		}
+	}
+	return nil
+}
+
+// suggest returns the supported option most likely meant by the unknown option
+// name, or an empty string if none is close enough. Like upstream, the first
+// option in alphabetical order with a Levenshtein distance under 3 is used.
+func (i *supportInfo) suggest(name string) string {
+	names := make([]string, 0, len(i.Options))
+	for _, o := range i.Options {
+		names = append(names, o.Name)
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		if levenshtein(name, n) < 3 {
+			return n
+		}
+	}
+	return ""
+}
+
+// validate returns whether v is a valid value for the option, and if not, a
+// description of the values expected.
+func (o *supportOption) validate(v any) (string, bool) {
+	if !o.Array {
+		return o.validateValue(v)
+	}
+
+	expected := "an array"
+	if o.Type == "string" || o.Type == "path" {
+		expected = "an array of strings"
+	}
+	vs := toSlice(v)
+	if vs == nil {
+		return expected, false
+	}
+	for _, e := range vs {
+		if _, ok := o.validateValue(e); !ok {
+			return expected, false
+		}
+	}
+	return "", true
+}
+
+func (o *supportOption) validateValue(v any) (string, bool) {
+	switch o.Type {
+	case "boolean":
+		_, ok := v.(bool)
+		return "true or false", ok
+	case "int":
+		expected := "an integer"
+		if o.Range != nil {
+			switch {
+			case o.Range.Start != nil && o.Range.End != nil:
+				expected = fmt.Sprintf("an integer between %s and %s",
+					formatNumber(*o.Range.Start), formatNumber(*o.Range.End))
+			case o.Range.Start != nil:
+				expected = "an integer greater than or equal to " + formatNumber(*o.Range.Start)
+			}
+		}
+		n, ok := toNumber(v)
+		if !ok || n != math.Trunc(n) {
+			return expected, false
+		}
+		if o.Range != nil {
+			if o.Range.Start != nil && n < *o.Range.Start || o.Range.End != nil && n > *o.Range.End {
+				return expected, false
+			}
+		}
+		return expected, true
+	case "choice":
+		choices := make([]string, 0, len(o.Choices))
+		ok := false
+		for _, c := range o.Choices {
+			choices = append(choices, describeValue(c.Value))
+			if equalValues(c.Value, v) {
+				ok = true
+			}
+		}
+		// Like upstream, choices are listed as "a", "b" or "c".
+		if len(choices) > 1 {
+			last := len(choices) - 1
+			choices = append(choices[:last-1], choices[last-1]+" or "+choices[last])
+		}
+		return strings.Join(choices, ", "), ok
+	case "string", "path":
+		_, ok := v.(string)
+		return "a string", ok
+	}
+	// Types we don't know about, such as those added by plugins, are passed
+	// through for prettier to handle.
+	return "", true
+}
+
+// toNumber returns v as a float64 if it is any of the number types produced by
+// the config decoders.
+func toNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// equalValues returns whether a choice value from support info, decoded from
+// JSON, equals v from a config file, comparing numbers by value.
+func equalValues(choice any, v any) bool {
+	if a, ok := toNumber(choice); ok {
+		b, ok := toNumber(v)
+		return ok && a == b
+	}
+	switch choice.(type) {
+	case string, bool, nil:
+		return choice == v
+	}
+	return false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+var identifierRe = regexp.MustCompile(`^[$_a-zA-Z][$_a-zA-Z0-9]*$`)
+
+// describeKey, describeValue and describePair format config in messages like
+// upstream, which uses JavaScript object literal syntax.
+func describeKey(k string) string {
+	if identifierRe.MatchString(k) {
+		return k
+	}
+	b, _ := json.Marshal(k)
+	return string(b)
+}
+
+func describeValue(v any) string {
+	if n, ok := toNumber(v); ok {
+		return formatNumber(n)
+	}
+	if vs := toSlice(v); vs != nil {
+		parts := make([]string, 0, len(vs))
+		for _, e := range vs {
+			parts = append(parts, describeValue(e))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	if m := toMap(v); m != nil {
+		if len(m) == 0 {
+			return "{}"
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, describeKey(k)+": "+describeValue(m[k]))
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func describePair(k string, v any) string {
+	return describeValue(map[string]any{k: v})
+}
+
+func toSlice(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case []string:
+		res := make([]any, 0, len(v))
+		for _, e := range v {
+			res = append(res, e)
+		}
+		return res
+	case []map[string]any:
+		res := make([]any, 0, len(v))
+		for _, e := range v {
+			res = append(res, e)
+		}
+		return res
+	}
+	return nil
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
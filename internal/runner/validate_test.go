@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSupportInfo is a subset of the support info of prettier as encoded by the
+// wasm guest.
+const testSupportInfo = `{"options": [
+	{"name": "bracketSpacing", "type": "boolean", "default": true},
+	{"name": "endOfLine", "type": "choice", "choices": [{"value": "lf"}, {"value": "crlf"}, {"value": "cr"}, {"value": "auto"}]},
+	{"name": "parser", "type": "choice", "choices": [{"value": "babel"}, {"value": "markdown"}]},
+	{"name": "plugins", "type": "path", "array": true},
+	{"name": "printWidth", "type": "int", "range": {"start": 0, "end": null, "step": 1}},
+	{"name": "tabWidth", "type": "int", "range": {"start": 0, "end": null, "step": 1}},
+	{"name": "useTabs", "type": "boolean"}
+]}`
+
+func TestValidateConfig(t *testing.T) {
+	var info supportInfo
+	require.NoError(t, json.Unmarshal([]byte(testSupportInfo), &info))
+
+	tests := []struct {
+		name string
+		cfg  map[string]any
+		exp  map[string]any
+		err  bool
+		log  string
+	}{
+		{
+			name: "valid",
+			cfg: map[string]any{
+				"$schema":        "http://json.schemastore.org/prettierrc",
+				"bracketSpacing": false,
+				"endOfLine":      "crlf",
+				"plugins":        []any{"a"},
+				"printWidth":     float64(100),
+				"tabWidth":       int64(4),
+				"overrides": []any{
+					map[string]any{"files": "*.md", "options": map[string]any{"useTabs": true}},
+				},
+			},
+			exp: map[string]any{
+				"$schema":        "http://json.schemastore.org/prettierrc",
+				"bracketSpacing": false,
+				"endOfLine":      "crlf",
+				"plugins":        []any{"a"},
+				"printWidth":     float64(100),
+				"tabWidth":       int64(4),
+				"overrides": []any{
+					map[string]any{"files": "*.md", "options": map[string]any{"useTabs": true}},
+				},
+			},
+		},
+		{
+			name: "unknown option with suggestion",
+			cfg:  map[string]any{"tabwidth": 4, "useTabs": true},
+			exp:  map[string]any{"useTabs": true},
+			log:  "Ignored unknown option { tabwidth: 4 }. Did you mean tabWidth?",
+		},
+		{
+			name: "unknown option without suggestion",
+			cfg:  map[string]any{"semicolons": false},
+			exp:  map[string]any{},
+			log:  "Ignored unknown option { semicolons: false }.\n",
+		},
+		{
+			name: "unknown option in override",
+			cfg: map[string]any{
+				"overrides": []any{
+					map[string]any{"files": "*.md", "options": map[string]any{"use-tabs": true}},
+				},
+			},
+			exp: map[string]any{
+				"overrides": []any{
+					map[string]any{"files": "*.md", "options": map[string]any{}},
+				},
+			},
+			log: `Ignored unknown option { "use-tabs": true }. Did you mean useTabs?`,
+		},
+		{
+			name: "string for int",
+			cfg:  map[string]any{"printWidth": "100"},
+			err:  true,
+			log:  `Invalid printWidth value. Expected an integer greater than or equal to 0, but received "100".`,
+		},
+		{
+			name: "fraction for int",
+			cfg:  map[string]any{"tabWidth": 2.5},
+			err:  true,
+			log:  "Invalid tabWidth value. Expected an integer greater than or equal to 0, but received 2.5.",
+		},
+		{
+			name: "out of range",
+			cfg:  map[string]any{"tabWidth": -1},
+			err:  true,
+			log:  "Invalid tabWidth value. Expected an integer greater than or equal to 0, but received -1.",
+		},
+		{
+			name: "invalid choice",
+			cfg:  map[string]any{"endOfLine": "windows"},
+			err:  true,
+			log:  `Invalid endOfLine value. Expected "lf", "crlf", "cr" or "auto", but received "windows".`,
+		},
+		{
+			name: "string for boolean",
+			cfg:  map[string]any{"useTabs": "true"},
+			err:  true,
+			log:  `Invalid useTabs value. Expected true or false, but received "true".`,
+		},
+		{
+			name: "string for array",
+			cfg:  map[string]any{"plugins": "a"},
+			err:  true,
+			log:  `Invalid plugins value. Expected an array of strings, but received "a".`,
+		},
+		{
+			name: "invalid override option",
+			cfg: map[string]any{
+				"overrides": []any{
+					map[string]any{"files": "*.md", "options": map[string]any{"useTabs": 1}},
+				},
+			},
+			err: true,
+			log: "Invalid useTabs value. Expected true or false, but received 1.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+			orig := describeValue(tc.cfg)
+			res, err := validateConfig(t.Context(), &info, ".prettierrc", tc.cfg)
+			if tc.err {
+				require.ErrorIs(t, err, errInvalidConfigFile)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.exp, res)
+			}
+			// The loaded config is left as is.
+			require.Equal(t, orig, describeValue(tc.cfg))
+			if tc.log == "" {
+				require.Empty(t, buf.String())
+			} else {
+				require.Contains(t, logMessages(t, &buf), tc.log)
+			}
+		})
+	}
+}
+
+// logMessages returns the messages logged to buf by a JSON handler, one per line.
+func logMessages(t *testing.T, buf *bytes.Buffer) string {
+	t.Helper()
+	var res strings.Builder
+	d := json.NewDecoder(buf)
+	for d.More() {
+		var rec struct {
+			Msg string `json:"msg"`
+		}
+		require.NoError(t, d.Decode(&rec))
+		res.WriteString(rec.Msg + "\n")
+	}
+	return res.String()
+}
+
+func TestLevenshtein(t *testing.T) {
+	require.Equal(t, 0, levenshtein("tabWidth", "tabWidth"))
+	require.Equal(t, 1, levenshtein("tabwidth", "tabWidth"))
+	require.Equal(t, 3, levenshtein("kitten", "sitting"))
+	require.Equal(t, 4, levenshtein("", "semi"))
+}
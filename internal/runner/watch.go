@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/wasilibs/go-prettier/v3/internal/gitignore"
+)
+
+// watchPollInterval is how often watched files are checked for changes. Polling
+// avoids platform-specific file notification APIs and is cheap for the number of
+// files go-prettier is intended to format. It also debounces changes, as a file
+// is only formatted once it is unchanged between two polls.
+const watchPollInterval = 500 * time.Millisecond
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func newFileStamp(fi os.FileInfo) fileStamp {
+	return fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+}
+
+type watchedFile struct {
+	path  expandedPath
+	stamp fileStamp
+}
+
+// watcher tracks changes to files between polls.
+type watcher struct {
+	files map[string]watchedFile
+	// pending are files that changed in the last poll and are waiting to settle.
+	pending map[string]struct{}
+}
+
+func newWatcher(files map[string]watchedFile) *watcher {
+	return &watcher{
+		files:   files,
+		pending: map[string]struct{}{},
+	}
+}
+
+// update records the current state of files, returning those that changed and
+// have since settled.
+func (w *watcher) update(files map[string]watchedFile) []expandedPath {
+	var ready []expandedPath
+	for p, f := range files {
+		if prev, ok := w.files[p]; !ok || prev.stamp != f.stamp {
+			w.pending[p] = struct{}{}
+			continue
+		}
+		if _, ok := w.pending[p]; ok {
+			ready = append(ready, f.path)
+			delete(w.pending, p)
+		}
+	}
+	for p := range w.pending {
+		if _, ok := files[p]; !ok {
+			delete(w.pending, p)
+		}
+	}
+	w.files = files
+	return ready
+}
+
+// touch records the current state of a file we changed ourselves, so that it is
+// not seen as changed.
+func (w *watcher) touch(p string, fi os.FileInfo) {
+	if f, ok := w.files[p]; ok {
+		f.stamp = newFileStamp(fi)
+		w.files[p] = f
+	}
+}
+
+// watch formats files matching args.Patterns whenever they change, until ctx is
+// done. Each poll only checks the files already known, and patterns are expanded
+// again when a directory they were expanded from or an ignore file changes, so
+// that new files are picked up and changes to ignore files are respected.
+func (r *Runner) watch(ctx context.Context, args RunArgs, cache *formatCache) error {
+	slog.InfoContext(ctx, "Watching for changes...")
+
+	deps := watchedDeps(ctx, args)
+	w := newWatcher(watchedFiles(ctx, args))
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var files map[string]watchedFile
+		if depsChanged(deps) {
+			deps = watchedDeps(ctx, args)
+			files = watchedFiles(ctx, args)
+		} else {
+			files = restatFiles(w.files)
+		}
+
+		ready := w.update(files)
+		if len(ready) == 0 {
+			continue
+		}
+
+		// Config files may have changed too so don't reuse a resolver.
+		resolver, err := newConfigResolver(ctx, args, r)
+		if err != nil {
+			continue
+		}
+		for _, p := range ready {
+			start := time.Now()
+			res, _ := r.format(ctx, p, resolver, args, cache)
+			if res.Status == fileStatusFormatted && args.Write {
+				slog.InfoContext(ctx, fmt.Sprintf("%s %dms", p.filePath, time.Since(start).Milliseconds()))
+			}
+			if fi, err := os.Stat(p.filePath); err == nil {
+				w.touch(p.filePath, fi)
+			}
+		}
+		if cache != nil {
+			if err := cache.save(); err != nil {
+				slog.WarnContext(ctx, err.Error())
+			}
+		}
+	}
+}
+
+// watchedFiles expands the patterns in args, returning the files that match.
+func watchedFiles(ctx context.Context, args RunArgs) map[string]watchedFile {
+	files := map[string]watchedFile{}
+	for _, p := range expandPatterns(ctx, args) {
+		// Errors such as unmatched patterns were reported by the initial run.
+		if p.error != "" {
+			continue
+		}
+		fi, err := os.Stat(p.filePath)
+		if err != nil {
+			continue
+		}
+		files[p.filePath] = watchedFile{
+			path:  p,
+			stamp: newFileStamp(fi),
+		}
+	}
+	return files
+}
+
+// restatFiles returns the current state of files, leaving out those that no
+// longer exist.
+func restatFiles(files map[string]watchedFile) map[string]watchedFile {
+	res := make(map[string]watchedFile, len(files))
+	for p, f := range files {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		f.stamp = newFileStamp(fi)
+		res[p] = f
+	}
+	return res
+}
+
+// watchedDeps returns the state of what expanding the patterns in args depends
+// on, the directories that are walked and the ignore files in them. Creating,
+// renaming or deleting a file changes the modification time of its directory.
+func watchedDeps(ctx context.Context, args RunArgs) map[string]fileStamp {
+	ignores := loadIgnoreFiles(ctx, args)
+	ignores = append(ignores, gitignore.NewMatcher(defaultIgnorePatterns(args)))
+
+	ignoreNames := map[string]bool{}
+	deps := map[string]fileStamp{}
+	add := func(p string) {
+		if fi, err := os.Stat(p); err == nil {
+			deps[p] = newFileStamp(fi)
+		}
+	}
+	for _, p := range args.IgnorePaths {
+		ignoreNames[filepath.Base(p)] = true
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(args.Cwd, p)
+		}
+		add(p)
+	}
+
+	var roots []string
+	for _, pattern := range args.Patterns {
+		if strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		pattern = filepath.Join(args.Cwd, pattern)
+		fi, err := os.Lstat(pattern)
+		switch {
+		case err == nil && fi.IsDir():
+			roots = append(roots, pattern)
+		case err == nil:
+			add(filepath.Dir(pattern))
+		default:
+			// A glob can match in any directory under its base.
+			base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+			roots = append(roots, filepath.FromSlash(base))
+		}
+	}
+	slices.Sort(roots)
+
+	for _, root := range slices.Compact(roots) {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr // Unreadable directories aren't watched
+			}
+			switch {
+			case d.IsDir() && ignoreAnyMatch(path, ignores, true):
+				return filepath.SkipDir
+			case d.IsDir(), ignoreNames[d.Name()]:
+				add(path)
+			}
+			return nil
+		})
+	}
+	return deps
+}
+
+// depsChanged returns whether any of deps, from watchedDeps, has changed.
+func depsChanged(deps map[string]fileStamp) bool {
+	for p, stamp := range deps {
+		fi, err := os.Stat(p)
+		if err != nil || newFileStamp(fi) != stamp {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	file := func(p string, size int64, modTime time.Time) watchedFile {
+		return watchedFile{path: expandedPath{filePath: p}, stamp: fileStamp{size: size, modTime: modTime}}
+	}
+	files := func(fs ...watchedFile) map[string]watchedFile {
+		res := map[string]watchedFile{}
+		for _, f := range fs {
+			res[f.path.filePath] = f
+		}
+		return res
+	}
+
+	w := newWatcher(files(file("a.md", 1, t0), file("b.md", 1, t0)))
+
+	// No changes.
+	require.Empty(t, w.update(files(file("a.md", 1, t0), file("b.md", 1, t0))))
+
+	// A change is only ready once the file is unchanged for a poll.
+	require.Empty(t, w.update(files(file("a.md", 2, t0.Add(time.Second)), file("b.md", 1, t0))))
+	require.Empty(t, w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 1, t0))))
+	require.Equal(t, []expandedPath{{filePath: "a.md"}},
+		w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 1, t0))))
+	require.Empty(t, w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 1, t0))))
+
+	// New files are formatted.
+	require.Empty(t, w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 1, t0), file("c.md", 1, t0))))
+	require.Equal(t, []expandedPath{{filePath: "c.md"}},
+		w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 1, t0), file("c.md", 1, t0))))
+
+	// Deleted files are forgotten.
+	require.Empty(t, w.update(files(file("a.md", 3, t0.Add(2*time.Second)), file("b.md", 2, t0.Add(time.Second)))))
+	require.Empty(t, w.update(files(file("a.md", 3, t0.Add(2*time.Second)))))
+	require.Empty(t, w.pending)
+}
+
+func TestWatchedDeps(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "sub/b.md", "node_modules/pkg/c.md", ".prettierignore"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte("a"), 0o644))
+	}
+	// Make sure changes are seen despite coarse modification times.
+	past := time.Now().Add(-time.Hour)
+	for _, name := range []string{".", "sub", "node_modules", ".prettierignore"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), past, past))
+	}
+
+	args := RunArgs{Cwd: dir, Patterns: []string{"."}, IgnorePaths: []string{".prettierignore"}}
+	deps := watchedDeps(t.Context(), args)
+	require.Contains(t, deps, dir)
+	require.Contains(t, deps, filepath.Join(dir, "sub"))
+	require.Contains(t, deps, filepath.Join(dir, ".prettierignore"))
+	require.NotContains(t, deps, filepath.Join(dir, "node_modules"))
+	require.NotContains(t, deps, filepath.Join(dir, "a.md"))
+
+	// Editing a formatted file doesn't need patterns to be expanded again.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("b"), 0o644))
+	require.False(t, depsChanged(deps))
+
+	// Neither does a change in an ignored directory.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "d.md"), []byte("a"), 0o644))
+	require.False(t, depsChanged(deps))
+
+	// A new file does.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "e.md"), []byte("a"), 0o644))
+	require.True(t, depsChanged(deps))
+
+	// As does editing an ignore file.
+	deps = watchedDeps(t.Context(), args)
+	require.False(t, depsChanged(deps))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".prettierignore"), []byte("sub"), 0o644))
+	require.True(t, depsChanged(deps))
+}
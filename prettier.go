@@ -0,0 +1,96 @@
+// Package prettier provides in-process access to the prettier distribution bundled
+// with go-prettier, for Go programs that want to format content without executing
+// the prettier CLI.
+package prettier
+
+import (
+	"context"
+	"maps"
+
+	"github.com/wasilibs/go-prettier/v3/internal/runner"
+)
+
+// ErrNoParser is returned by Formatter.Format when no parser is configured and
+// none could be inferred from the file path.
+var ErrNoParser = runner.ErrNoParser
+
+// SyntaxError is returned by Formatter.Format when prettier fails to parse the
+// content being formatted.
+type SyntaxError = runner.SyntaxError
+
+// Options configures the formatting of a single file.
+type Options struct {
+	// Config contains prettier options in the same form as a .prettierrc file,
+	// for example {"proseWrap": "always", "tabWidth": 4}. Configuration files on
+	// disk, including .editorconfig, are not consulted.
+	Config map[string]any
+
+	// Parser forces the parser to use, for example "yaml". If empty, the parser
+	// is inferred from the file path.
+	Parser string
+
+	// RangeStart and RangeEnd are byte offsets in src that restrict formatting to
+	// the code between them, extended to the statements or nodes that enclose the
+	// range. A zero RangeEnd formats to the end of src.
+	RangeStart int
+	RangeEnd   int
+}
+
+// Formatter formats content using prettier. A Formatter is safe for concurrent
+// use and should be reused, as creating one compiles the prettier Wasm module.
+type Formatter struct {
+	r *runner.Runner
+}
+
+// NewFormatter returns a new Formatter.
+func NewFormatter() *Formatter {
+	return &Formatter{r: runner.NewRunner()}
+}
+
+// Format formats src with prettier. filepath is used to infer the parser and does
+// not need to exist. ErrNoParser is returned if no parser could be inferred, and
+// a *SyntaxError if src could not be parsed.
+func (f *Formatter) Format(ctx context.Context, src []byte, filepath string, opts Options) ([]byte, error) {
+	return f.r.Format(ctx, src, filepath, opts.config(src)) //nolint:wrapcheck
+}
+
+// FormatWithCursor is like Format but also returns where the cursor at the byte
+// offset cursorOffset in src is in the formatted content, so that editors can
+// keep the cursor in place.
+func (f *Formatter) FormatWithCursor(ctx context.Context, src []byte, filepath string, cursorOffset int, opts Options) ([]byte, int, error) {
+	cfg := opts.config(src)
+	cfg["cursorOffset"] = runner.UTF16Offset(src, cursorOffset)
+
+	res, resOffset, err := f.r.FormatWithCursor(ctx, src, filepath, cfg)
+	if err != nil {
+		return nil, 0, err //nolint:wrapcheck
+	}
+	if resOffset < 0 {
+		return res, 0, nil
+	}
+	return res, runner.ByteOffset(res, resOffset), nil
+}
+
+// config returns the prettier config for opts, converting offsets in src to the
+// UTF-16 offsets prettier uses.
+func (o Options) config(src []byte) map[string]any {
+	cfg := maps.Clone(o.Config)
+	if cfg == nil {
+		cfg = map[string]any{}
+	}
+	if o.Parser != "" {
+		cfg["parser"] = o.Parser
+	}
+	if o.RangeStart > 0 {
+		cfg["rangeStart"] = runner.UTF16Offset(src, o.RangeStart)
+	}
+	if o.RangeEnd > 0 {
+		cfg["rangeEnd"] = runner.UTF16Offset(src, o.RangeEnd)
+	}
+	return cfg
+}
+
+// Close releases the resources held by the Formatter.
+func (f *Formatter) Close(ctx context.Context) error {
+	return f.r.Close(ctx) //nolint:wrapcheck
+}
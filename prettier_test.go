@@ -3,16 +3,21 @@ package prettier
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/wasilibs/go-prettier/v3/internal/git/gittest"
 	"github.com/wasilibs/go-prettier/v3/internal/runner"
 )
 
@@ -91,6 +96,53 @@ func TestRun(t *testing.T) {
 			},
 			expFS: expFilesTabWidth4,
 		},
+		{
+			name: "package.json config, write",
+			prepare: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "test", "prettier": {"tabWidth": 4}}`), 0o644)
+			},
+			args: runner.RunArgs{
+				Patterns: []string{"."},
+				Write:    true,
+			},
+			expFS: expFilesTabWidth4,
+		},
+		{
+			name: "json5 config, write",
+			prepare: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, ".prettierrc.json5"), []byte("// Indent more\n{tabWidth: 4,}\n"), 0o644)
+			},
+			args: runner.RunArgs{
+				Patterns: []string{"."},
+				Write:    true,
+			},
+			expFS: expFilesTabWidth4,
+		},
+		{
+			name: "cjs config, write",
+			prepare: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "prettier.config.cjs"), []byte("module.exports = { tabWidth: 4 };\n"), 0o644)
+			},
+			args: runner.RunArgs{
+				Patterns: []string{"."},
+				Write:    true,
+			},
+			expFS: expFilesTabWidth4,
+		},
+		{
+			name: "esm config with relative import, write",
+			prepare: func(dir string) error {
+				if err := os.WriteFile(filepath.Join(dir, "base.cjs"), []byte("module.exports = { tabWidth: 4 };\n"), 0o644); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, ".prettierrc.mjs"), []byte("import base from \"./base.cjs\";\n\nexport default { ...base };\n"), 0o644)
+			},
+			args: runner.RunArgs{
+				Patterns: []string{"."},
+				Write:    true,
+			},
+			expFS: expFilesTabWidth4,
+		},
 		{
 			name: "editorconfig, write",
 			prepare: func(dir string) error {
@@ -172,3 +224,441 @@ func TestRunStdin(t *testing.T) {
 	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
 	require.Equal(t, "{ \"a\": 1, \"b\": [1, 2, 3] }\n", stdout.String()) //nolint:testifylint // exact formatting, not JSON equality
 }
+
+func TestRunStdinOptions(t *testing.T) {
+	t.Parallel()
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--stdin-filepath=_.md", "--print-width", "5", "--prose-wrap=always")
+	cmd.Stdin = strings.NewReader("aaa bbb ccc\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+	require.Equal(t, "aaa\nbbb\nccc\n", stdout.String())
+}
+
+func TestRunStdinPragma(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		flag     string
+		in       string
+		expected string
+	}{
+		{flag: "--insert-pragma", in: "a   = 1\n", expected: "/** @format */\n\na = 1;\n"},
+		{flag: "--require-pragma", in: "a   = 1\n", expected: "a   = 1\n"},
+		{flag: "--require-pragma", in: "/** @format */\na   = 1\n", expected: "/** @format */\na = 1;\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.flag, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--stdin-filepath=_.js", tc.flag)
+			cmd.Stdin = strings.NewReader(tc.in)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+			require.Equal(t, tc.expected, stdout.String())
+		})
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	t.Parallel()
+
+	f := NewFormatter()
+	defer f.Close(t.Context())
+
+	t.Run("yaml", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := f.Format(t.Context(), []byte("a:   1\nb:\n    - c\n"), "config.yaml", Options{})
+		require.NoError(t, err)
+		require.Equal(t, "a: 1\nb:\n  - c\n", string(res))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := f.Format(t.Context(), []byte("aaa bbb ccc"), "notes.txt", Options{
+			Config: map[string]any{"printWidth": 5, "proseWrap": "always"},
+			Parser: "markdown",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "aaa\nbbb\nccc\n", string(res))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+
+		src := "const a  =  1;\nconst b  =  2;\n"
+		res, err := f.Format(t.Context(), []byte(src), "index.js", Options{
+			RangeStart: strings.Index(src, "const b"),
+			RangeEnd:   len(src),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "const a  =  1;\nconst b = 2;\n", string(res))
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		t.Parallel()
+
+		// é is multiple bytes to make sure offsets are converted from UTF-16.
+		src := "const   é = 1;"
+		res, cursor, err := f.FormatWithCursor(t.Context(), []byte(src), "index.js", strings.Index(src, "1"), Options{})
+		require.NoError(t, err)
+		require.Equal(t, "const é = 1;\n", string(res))
+		require.Equal(t, strings.Index(string(res), "1"), cursor)
+	})
+
+	t.Run("no parser", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := f.Format(t.Context(), []byte("hello"), "README.unknown", Options{})
+		require.ErrorIs(t, err, ErrNoParser)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := f.Format(t.Context(), []byte("a: [1, 2"), "config.yaml", Options{})
+		var syntaxErr *SyntaxError
+		require.ErrorAs(t, err, &syntaxErr)
+		require.Equal(t, "config.yaml", syntaxErr.Path)
+		require.NotNil(t, syntaxErr.Loc)
+		require.Equal(t, 1, syntaxErr.Loc.Start.Line)
+	})
+}
+
+func TestRunStdinSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-color", "--no-config", "--no-editorconfig", "--stdin-filepath=broken.json")
+	cmd.Stdin = strings.NewReader("{\"a\": 1,,}\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.Error(t, cmd.Run())
+	require.Contains(t, stderr.String(), "[error] broken.json:1:10: ")
+	require.Contains(t, stderr.String(), "[error] > 1 | {\"a\": 1,,}\n[error]     |          ^\n")
+}
+
+func TestRunStdinParser(t *testing.T) {
+	t.Parallel()
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--parser", "yaml")
+	cmd.Stdin = strings.NewReader("a:   1\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+	require.Equal(t, "a: 1\n", stdout.String())
+}
+
+func TestRunStdinCursorOffset(t *testing.T) {
+	t.Parallel()
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--parser", "yaml", "--cursor-offset", "5")
+	cmd.Stdin = strings.NewReader("a:   1\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+	require.Equal(t, "a: 1\n", stdout.String())
+	require.Equal(t, "3\n", stderr.String())
+}
+
+func TestRunInvalidParser(t *testing.T) {
+	t.Parallel()
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-color", "--parser", "yml", "--stdin-filepath", "_.yaml")
+	cmd.Stdin = strings.NewReader("a:   1\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.Error(t, cmd.Run())
+	require.Contains(t, stderr.String(), `[error] Invalid --parser value. Expected one of the following values, but received "yml".`)
+	require.Contains(t, stderr.String(), `[error] - "yaml"`)
+}
+
+func TestRunListDifferent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "formatted.json"), []byte("{ \"a\": 1 }\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unformatted.json"), []byte(`{"a":1}`), 0o644))
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "-l", dir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, cmd.Run(), &exitErr, "stderr: %s", stderr.String())
+	require.Equal(t, 1, exitErr.ExitCode())
+	require.Equal(t, filepath.Join(dir, "unformatted.json")+"\n", stdout.String())
+}
+
+func TestRunListDifferentWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "formatted.json"), []byte("{ \"a\": 1 }\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unformatted.json"), []byte(`{"a":1}`), 0o644))
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "-l", "--write", dir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Like upstream, files fixed by --write are listed without failing.
+	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+	require.Equal(t, filepath.Join(dir, "unformatted.json")+"\n", stdout.String())
+
+	b, err := os.ReadFile(filepath.Join(dir, "unformatted.json"))
+	require.NoError(t, err)
+	require.Equal(t, "{ \"a\": 1 }\n", string(b))
+}
+
+func TestRunDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "formatted.json"), []byte("{ \"a\": 1 }\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unformatted.json"), []byte(`{"a":1}`), 0o644))
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--no-color", "--diff", dir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, cmd.Run(), &exitErr, "stderr: %s", stderr.String())
+	require.Equal(t, 1, exitErr.ExitCode())
+
+	p := filepath.ToSlash(filepath.Join(dir, "unformatted.json"))
+	require.Equal(t, "--- a/"+p+"\n+++ b/"+p+"\n"+
+		"@@ -1 +1 @@\n"+
+		"-{\"a\":1}\n\\ No newline at end of file\n"+
+		"+{ \"a\": 1 }\n", stdout.String())
+}
+
+func TestRunReporter(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "formatted.json"), []byte("{ \"a\": 1 }\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"a": 1,,}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unformatted.json"), []byte(`{"a":1}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unknown.foo"), []byte("foo"), 0o644))
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "--no-config", "--no-editorconfig", "--check", "--reporter", "json", dir)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, cmd.Run(), &exitErr, "stderr: %s", stderr.String())
+	require.Equal(t, 1, exitErr.ExitCode())
+
+	var report struct {
+		Files []struct {
+			Path   string `json:"path"`
+			Status string `json:"status"`
+			Loc    *struct {
+				Start struct {
+					Line int `json:"line"`
+				} `json:"start"`
+			} `json:"loc"`
+		} `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report), stdout.String())
+	require.Len(t, report.Files, 4)
+
+	statuses := map[string]string{}
+	for _, f := range report.Files {
+		statuses[path.Base(f.Path)] = f.Status
+		if f.Status == "error" {
+			require.NotNil(t, f.Loc)
+			require.Equal(t, 1, f.Loc.Start.Line)
+		}
+	}
+	require.Equal(t, map[string]string{
+		"formatted.json":   "unchanged",
+		"invalid.json":     "error",
+		"unformatted.json": "formatted",
+		"unknown.foo":      "unsupported",
+	}, statuses)
+}
+
+func TestRunWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupting a process is not supported on Windows")
+	}
+	t.Parallel()
+
+	// Build rather than go run so that the interrupt reaches prettier.
+	bin := filepath.Join(t.TempDir(), "prettier")
+	out, err := exec.Command("go", "build", "-o", bin, "./cmd/prettier").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a":1}`), 0o644))
+
+	cmd := exec.Command(bin, "--no-config", "--no-editorconfig", "--write", "--watch", dir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	require.NoError(t, cmd.Start())
+
+	formatted := func(name string) func() bool {
+		return func() bool {
+			b, err := os.ReadFile(filepath.Join(dir, name))
+			return err == nil && string(b) == "{ \"a\": 1 }\n"
+		}
+	}
+
+	// Formatted by the initial run.
+	require.Eventually(t, formatted("a.json"), 30*time.Second, 100*time.Millisecond)
+
+	// Formatted when changed.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a":   1}`), 0o644))
+	require.Eventually(t, formatted("a.json"), 30*time.Second, 100*time.Millisecond)
+
+	// Formatted when created.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"a":1}`), 0o644))
+	require.Eventually(t, formatted("b.json"), 30*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, cmd.Process.Signal(os.Interrupt))
+	require.NoError(t, cmd.Wait(), "stderr: %s", stderr.String())
+}
+
+func TestRunStaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Parallel()
+
+	// Build rather than go run so that prettier runs in the repository.
+	bin := filepath.Join(t.TempDir(), "prettier")
+	out, err := exec.Command("go", "build", "-o", bin, "./cmd/prettier").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	dir := t.TempDir()
+	run := func(name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		cmd.Env = gittest.Env()
+		// Like a git hook run by a GUI client or in CI, stdin is not a terminal.
+		cmd.Stdin = strings.NewReader("")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	run("git", "init", "-q")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "clean.json"), []byte(`{"a":1}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.json"), []byte(`{"b":1}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unstaged.json"), []byte(`{"c":1}`), 0o644))
+	run("git", "add", "clean.json", "partial.json")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.json"), []byte(`{"b":2}`), 0o644))
+
+	// Without patterns, as in a pre-commit hook.
+	run(bin, "--no-config", "--no-editorconfig", "--staged", "--write")
+
+	require.Equal(t, "{ \"a\": 1 }\n", run("git", "show", ":clean.json"))
+	require.Equal(t, "{ \"b\": 1 }\n", run("git", "show", ":partial.json"))
+
+	// Only files without unstaged changes are formatted in the working tree.
+	b, err := os.ReadFile(filepath.Join(dir, "clean.json"))
+	require.NoError(t, err)
+	require.Equal(t, "{ \"a\": 1 }\n", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "partial.json"))
+	require.NoError(t, err)
+	require.Equal(t, `{"b":2}`, string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "unstaged.json"))
+	require.NoError(t, err)
+	require.Equal(t, `{"c":1}`, string(b))
+}
+
+func TestLSP(t *testing.T) {
+	t.Parallel()
+
+	var in bytes.Buffer
+	send := func(msg string) {
+		_, _ = fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	send(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	send(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///tmp/config.yaml","text":"a:   1\n"}}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///tmp/config.yaml"}}}`)
+	send(`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`)
+	send(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	cmd := exec.Command("go", "run", "./cmd/prettier", "lsp", "--no-config", "--no-editorconfig")
+	cmd.Stdin = &in
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+	require.Contains(t, stdout.String(), `{"jsonrpc":"2.0","id":2,"result":[{"range":{"start":{"line":0,"character":3},"end":{"line":0,"character":5}},"newText":""}]}`)
+}
+
+func TestRunIntrospection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		exp  string
+	}{
+		{
+			name: "find config path",
+			args: []string{"--find-config-path", "testdata/config/README.md"},
+			exp:  "testdata/config/.prettierrc\n",
+		},
+		{
+			name: "print config",
+			args: []string{"--print-config", "testdata/config/README.md"},
+			exp:  "{ \"filepath\": \"testdata/config/README.md\", \"tabWidth\": 4, \"useTabs\": false }\n",
+		},
+		{
+			name: "file info",
+			args: []string{"--file-info", "testdata/in/test.md"},
+			exp:  "{ \"ignored\": false, \"inferredParser\": \"markdown\" }\n",
+		},
+		{
+			name: "file info unknown",
+			args: []string{"--file-info", "testdata/in/test.unknown"},
+			exp:  "{ \"ignored\": false, \"inferredParser\": null }\n",
+		},
+		{
+			name: "file info ignored",
+			args: []string{"--file-info", "node_modules/pkg/index.js"},
+			exp:  "{ \"ignored\": true, \"inferredParser\": null }\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := exec.Command("go", append([]string{"run", "./cmd/prettier"}, tc.args...)...)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+			require.Equal(t, tc.exp, stdout.String())
+		})
+	}
+}